@@ -3,6 +3,8 @@ package client
 import (
 	"context"
 	"net/http"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // ChatRepo (Chat Repository) defines the interface for chat repository operations
@@ -42,6 +44,14 @@ type PromptRepo interface {
 	AllPrompts() []*PromptItem
 	UpdatePromptByName(item *PromptItem) error
 	DeletePromptByName(name string) error
+	RenamePromptByName(name string, item *PromptItem) error
+}
+
+// ReloadableRepo is implemented by the file repositories, whose cache is reloaded
+// after the file is changed outside the repository, e.g. by an editor or another k-cli
+type ReloadableRepo interface {
+	Path() string
+	Reload() error
 }
 
 // StreamChunk defines a chunk of a stream
@@ -50,16 +60,32 @@ type StreamChunk struct {
 	// Provider string
 	Model string
 
-	Content string // The content of the chunk
-	Done    bool   // Whether the stream is done
-	Error   error  // Any error that occurred
+	Content            string           // The content of the chunk
+	ReasoningContent   string           // The reasoning (thinking) content of the chunk
+	ReasoningSignature string           // The signature of the reasoning, sent back to the provider as is
+	ToolCalls          []*ToolCallDelta // Fragments of the native tool calls in the chunk
+	Usage              *Usage           // The token usage of the response, the last one in the stream wins
+	Done               bool             // Whether the stream is done
+	Error              error            // Any error that occurred
+}
+
+// ToolCallDelta is a fragment of a native tool call. The fragments with the same Index
+// belong to the same tool call, their Arguments are concatenated in order.
+type ToolCallDelta struct {
+	Index     int
+	ID        string
+	Name      string
+	Arguments string
 }
 
 type Provider interface {
+	// CallStreamableChatCompletions returns the assistant message of the messages,
+	// canceling ctx aborts the request and the stream
 	CallStreamableChatCompletions(
+		ctx context.Context,
 		messages []*Message,
 		prompt *string,
-	) *Message
+	) (*Message, error)
 
 	BuildRequest(
 		_ context.Context,
@@ -68,3 +94,14 @@ type Provider interface {
 		_ *string,
 	) (*http.Request, error)
 }
+
+// NativeToolCaller is implemented by the providers supporting native function calling,
+// which receive the MCP tools in the request instead of the XML tool use protocol in the system prompt
+type NativeToolCaller interface {
+	CallStreamableChatCompletionsWithTools(
+		ctx context.Context,
+		messages []*Message,
+		prompt *string,
+		tools []*mcp.Tool,
+	) (*Message, error)
+}
@@ -7,8 +7,11 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/kydenul/log"
+	"github.com/spf13/cast"
 )
 
+const DefaultChatTitleLength = 50
+
 type Chat struct {
 	ID         string    `json:"id"`
 	CreateTime time.Time `json:"create_time"`
@@ -29,6 +32,24 @@ func (c *Chat) UpdateMessages(messages []*Message) {
 	c.UpdateTime = GetISO8601Timestamp()
 }
 
+// Title returns the first user message of the chat, truncated to DefaultChatTitleLength runes
+func (c *Chat) Title() string {
+	for _, msg := range c.Messages {
+		if msg.Role != RoleUser {
+			continue
+		}
+
+		title := strings.Join(strings.Fields(cast.ToString(msg.Content)), " ")
+		if runes := []rune(title); len(runes) > DefaultChatTitleLength {
+			title = string(runes[:DefaultChatTitleLength]) + "..."
+		}
+
+		return title
+	}
+
+	return ""
+}
+
 // ----------------------------------------------------------------------------
 
 type ChatSvr struct {
@@ -100,10 +121,9 @@ func (svr *ChatSvr) DeleteChat(ctx context.Context, chatID string) (bool, error)
 	return svr.repo.DeleteChat(ctx, chatID)
 }
 
-// TODO: Implement
+// GenerateShareHTML renders the chat with the specified ID as a standalone HTML page
 func (svr *ChatSvr) GenerateShareHTML(ctx context.Context, chatID string) (string, error) {
-	svr.Warn("GenerateShareHTML not implemented", chatID, ctx)
-	return "TODO-implement", nil
+	return svr.ExportChat(ctx, chatID, ExportFormatHTML)
 }
 
 // GetUnixTimestamp returns current time as 13-digit unix timestamp (milliseconds)
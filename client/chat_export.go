@@ -0,0 +1,206 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cast"
+)
+
+const (
+	ExportFormatMarkdown = "md"
+	ExportFormatJSON     = "json"
+	ExportFormatHTML     = "html"
+
+	exportTimeLayout = "2006-01-02 15:04:05"
+)
+
+// ExportFormats lists the supported chat export formats
+var ExportFormats = []string{ExportFormatMarkdown, ExportFormatJSON, ExportFormatHTML}
+
+// ExportChat renders the chat with the specified ID in the given format: md, json or html
+func (svr *ChatSvr) ExportChat(ctx context.Context, chatID, format string) (string, error) {
+	chat, err := svr.Chat(ctx, chatID)
+	if err != nil {
+		return "", err
+	}
+	if chat == nil {
+		return "", fmt.Errorf("chat %s not found", chatID)
+	}
+
+	switch format {
+	case ExportFormatMarkdown:
+		return ChatToMarkdown(chat), nil
+
+	case ExportFormatJSON:
+		data, err := sonic.MarshalIndent(chat, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal chat %s: %w", chatID, err)
+		}
+		return string(data), nil
+
+	case ExportFormatHTML:
+		return ChatToHTML(chat)
+
+	default:
+		return "", fmt.Errorf("unsupported export format '%s', expected one of %v", format, ExportFormats)
+	}
+}
+
+// ImportChat adds a chat exported in JSON format. It fails if a chat with the same ID exists.
+func (svr *ChatSvr) ImportChat(ctx context.Context, chat *Chat) (*Chat, error) {
+	if chat == nil || len(chat.Messages) == 0 {
+		return nil, errors.New("chat is empty")
+	}
+
+	if chat.ID == "" {
+		chat.ID = GenerateChatID()
+	} else if exist, err := svr.Chat(ctx, chat.ID); err != nil {
+		return nil, err
+	} else if exist != nil {
+		return nil, fmt.Errorf("chat %s already exists", chat.ID)
+	}
+
+	if chat.CreateTime.IsZero() {
+		chat.CreateTime = svr.createTimeStamp()
+	}
+	if chat.UpdateTime.IsZero() {
+		chat.UpdateTime = chat.CreateTime
+	}
+
+	return svr.repo.AddChat(ctx, chat)
+}
+
+// ChatToMarkdown renders the chat as a Markdown document
+func ChatToMarkdown(chat *Chat) string {
+	var builder strings.Builder
+
+	title := chat.Title()
+	if title == "" {
+		title = "Chat " + chat.ID
+	}
+
+	fmt.Fprintf(&builder, "# %s\n\n", title)
+	fmt.Fprintf(&builder, "- ID: `%s`\n", chat.ID)
+	fmt.Fprintf(&builder, "- Created: %s\n", chat.CreateTime.Format(exportTimeLayout))
+	fmt.Fprintf(&builder, "- Updated: %s\n", chat.UpdateTime.Format(exportTimeLayout))
+
+	for _, msg := range chat.Messages {
+		fmt.Fprintf(&builder, "\n## %s\n\n", messageHeading(msg))
+
+		if reasoning := strings.TrimSpace(msg.Reasoning()); reasoning != "" {
+			fmt.Fprintf(&builder, "<details>\n<summary>Reasoning</summary>\n\n%s\n\n</details>\n\n", reasoning)
+		}
+
+		if content := strings.TrimSpace(cast.ToString(msg.Content)); content != "" {
+			builder.WriteString(content + "\n")
+		}
+
+		if msg.Role == RoleAssistant && msg.Tool != "" {
+			args, _ := sonic.MarshalIndent(msg.Arguments, "", "  ")
+			fmt.Fprintf(&builder, "\nCalling `%s` on `%s`:\n\n```json\n%s\n```\n", msg.Tool, msg.Server, args)
+		}
+		for _, call := range msg.ToolCalls {
+			fmt.Fprintf(&builder, "\nCalling `%s`:\n\n```json\n%s\n```\n", call.Function.Name, call.Function.Arguments)
+		}
+	}
+
+	return builder.String()
+}
+
+// htmlMessage is a message prepared for the HTML export template
+type htmlMessage struct {
+	Role    string
+	Heading string
+	Content string
+}
+
+var chatHTMLTemplate = template.Must(template.New("chat").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; max-width: 860px; margin: 2em auto; }
+.meta { color: #666; font-size: 0.9em; }
+.message { border-radius: 8px; padding: 0.8em 1em; margin: 1em 0; }
+.user { background: #eef5ff; }
+.assistant { background: #f5f5f5; }
+.tool { background: #fff8e6; }
+.heading { font-weight: bold; margin-bottom: 0.5em; }
+.content { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">{{.ID}} &middot; created {{.Created}} &middot; updated {{.Updated}}</p>
+{{range .Messages}}<div class="message {{.Role}}">
+<div class="heading">{{.Heading}}</div>
+<div class="content">{{.Content}}</div>
+</div>
+{{end}}</body>
+</html>
+`))
+
+// ChatToHTML renders the chat as a standalone HTML page
+func ChatToHTML(chat *Chat) (string, error) {
+	title := chat.Title()
+	if title == "" {
+		title = "Chat " + chat.ID
+	}
+
+	messages := make([]htmlMessage, 0, len(chat.Messages))
+	for _, msg := range chat.Messages {
+		messages = append(messages, htmlMessage{
+			Role:    msg.Role,
+			Heading: messageHeading(msg),
+			Content: strings.TrimSpace(cast.ToString(msg.Content)),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := chatHTMLTemplate.Execute(&buf, map[string]any{
+		"Title":    title,
+		"ID":       chat.ID,
+		"Created":  chat.CreateTime.Format(exportTimeLayout),
+		"Updated":  chat.UpdateTime.Format(exportTimeLayout),
+		"Messages": messages,
+	}); err != nil {
+		return "", fmt.Errorf("failed to render chat %s: %w", chat.ID, err)
+	}
+
+	return buf.String(), nil
+}
+
+// messageHeading returns a short heading describing the author of the message
+func messageHeading(msg *Message) string {
+	var heading string
+	switch msg.Role {
+	case RoleUser:
+		heading = "👤 User"
+	case RoleAssistant:
+		heading = "🤖 Assistant"
+	case RoleTool:
+		heading = "🔧 Tool"
+		if msg.Tool != "" {
+			heading += fmt.Sprintf(" %s/%s", msg.Server, msg.Tool)
+		}
+	default:
+		heading = msg.Role
+	}
+
+	if msg.Role == RoleAssistant && msg.Model != "" {
+		heading += " (" + msg.Model + ")"
+	}
+
+	if msg.Timestamp != nil && !msg.Timestamp.IsZero() {
+		heading += " · " + msg.Timestamp.Local().Format(exportTimeLayout)
+	}
+
+	return heading
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func newTestChatSvr(t *testing.T) *ChatSvr {
+	repo, err := NewChatFileRepository(createTempFile(t), 2, &discardLogger{})
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	t.Cleanup(func() { _ = repo.Close() })
+
+	return NewChatSvr(repo, &discardLogger{})
+}
+
+func TestChatSvr_ExportChat(t *testing.T) {
+	svr := newTestChatSvr(t)
+	ctx := context.Background()
+
+	chat := createTestChat("export-1")
+	chat.Messages = append(chat.Messages, &Message{
+		Role:    RoleAssistant,
+		Content: "<b>answer</b>",
+		Model:   "gpt-4",
+	})
+	if _, err := svr.ImportChat(ctx, chat); err != nil {
+		t.Fatalf("ImportChat() error = %v", err)
+	}
+
+	tests := []struct {
+		format   string
+		contains []string
+	}{
+		{
+			format:   ExportFormatMarkdown,
+			contains: []string{"# Test message for chat export-1", "## 👤 User", "## 🤖 Assistant (gpt-4)", "<b>answer</b>"},
+		},
+		{
+			format:   ExportFormatJSON,
+			contains: []string{`"id": "export-1"`, `"content": "<b>answer</b>"`},
+		},
+		{
+			format:   ExportFormatHTML,
+			contains: []string{"<!DOCTYPE html>", `class="message assistant"`, "&lt;b&gt;answer&lt;/b&gt;"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := svr.ExportChat(ctx, chat.ID, tt.format)
+			if err != nil {
+				t.Fatalf("ExportChat() error = %v", err)
+			}
+
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("ExportChat(%s) should contain %q, got:\n%s", tt.format, want, got)
+				}
+			}
+		})
+	}
+
+	if _, err := svr.ExportChat(ctx, chat.ID, "pdf"); err == nil {
+		t.Errorf("ExportChat() should fail for unsupported format")
+	}
+	if _, err := svr.ExportChat(ctx, "non-existent", ExportFormatMarkdown); err == nil {
+		t.Errorf("ExportChat() should fail for non-existent chat")
+	}
+}
+
+func TestChatSvr_ImportChat(t *testing.T) {
+	svr := newTestChatSvr(t)
+	ctx := context.Background()
+
+	// Without ID and times
+	chat := &Chat{Messages: []*Message{{Role: RoleUser, Content: "hello"}}}
+	imported, err := svr.ImportChat(ctx, chat)
+	if err != nil {
+		t.Fatalf("ImportChat() error = %v", err)
+	}
+	if imported.ID == "" || imported.CreateTime.IsZero() || imported.UpdateTime.IsZero() {
+		t.Errorf("ImportChat() should fill ID and times, got %+v", imported)
+	}
+
+	// Duplicate ID
+	if _, err := svr.ImportChat(ctx, &Chat{ID: imported.ID, Messages: chat.Messages}); err == nil {
+		t.Errorf("ImportChat() should fail for existing chat")
+	}
+
+	// Empty chat
+	if _, err := svr.ImportChat(ctx, &Chat{ID: "empty"}); err == nil {
+		t.Errorf("ImportChat() should fail for empty chat")
+	}
+}
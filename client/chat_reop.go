@@ -1,7 +1,6 @@
 package client
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
@@ -11,7 +10,6 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/bytedance/sonic"
 	"github.com/kydenul/log"
 	"github.com/spf13/cast"
 )
@@ -25,7 +23,6 @@ const (
 	opAddChat
 	opUpdateChat
 	opDeleteChat
-	opShutdown
 )
 
 const (
@@ -43,11 +40,15 @@ type ListChatsOption struct {
 
 // opReq (operation request) represents an async operation request
 type opReq struct {
+	ctx      context.Context // 请求方的 context，出队时已结束则不再执行
 	opType   opType
 	data     any
 	resultCh chan OpResp
 }
 
+// ErrRepoClosed is returned by the operations of a closed repository
+var ErrRepoClosed = errors.New("repository is shutdown")
+
 // OpResp (operation response) represents the result of an async operation
 type OpResp struct {
 	Data  any
@@ -66,10 +67,10 @@ type FileRepo struct {
 	cache   map[string]*Chat // In-memory cache
 	cacheMu sync.RWMutex     // Separate mutex for cache operations
 
-	opCh     chan opReq     // Channel for async operations => operation queue
+	opCh     chan opReq     // Channel for async operations => operation queue, closed by Close
 	workerWg sync.WaitGroup // WaitGroup for worker goroutines
 
-	shutdownCh chan struct{} // Channel to signal shutdown
+	// NOTE: The senders hold the read lock while enqueueing, so that opCh is never closed under them
 	isShutdown bool
 	shutdownMu sync.RWMutex
 }
@@ -92,10 +93,9 @@ func NewChatFileRepository(
 	fr := &FileRepo{
 		logger: logger,
 
-		dataFile:   dataFile,
-		cache:      make(map[string]*Chat),
-		opCh:       make(chan opReq, DefaultOperationQueueSize),
-		shutdownCh: make(chan struct{}),
+		dataFile: dataFile,
+		cache:    make(map[string]*Chat),
+		opCh:     make(chan opReq, DefaultOperationQueueSize),
 	}
 
 	// Initialize the file
@@ -114,32 +114,34 @@ func NewChatFileRepository(
 	}
 	for i := 0; i < workerCount; i++ {
 		fr.workerWg.Add(1)
-		go fr.worker(context.Background(), i)
+		go fr.worker(i)
 	}
 
 	return fr, nil
 }
 
-// worker processes async operations
-func (fr *FileRepo) worker(ctx context.Context, workerID int) {
+// worker processes the async operations until opCh is closed and drained.
+// The workers bound the number of operations processed concurrently.
+func (fr *FileRepo) worker(workerID int) {
 	defer fr.workerWg.Done()
 
-	log.Infof("Worker %d started\n", workerID)
-
-	for {
-		select {
-		case <-fr.shutdownCh:
-			log.Infof("Worker %d shutting down\n", workerID)
-			return
+	fr.logger.Infof("Worker %d started", workerID)
 
-		case req := <-fr.opCh:
-			fr.processOperation(ctx, req)
-		}
+	for req := range fr.opCh {
+		fr.processOperation(req)
 	}
+
+	fr.logger.Infof("Worker %d shutting down", workerID)
 }
 
-// processOperation processes a single operation
-func (fr *FileRepo) processOperation(ctx context.Context, req opReq) {
+// processOperation processes a single operation, unless the context of the request is done
+func (fr *FileRepo) processOperation(req opReq) {
+	// NOTE: resultCh is buffered, the result never blocks the worker
+	if err := req.ctx.Err(); err != nil {
+		req.resultCh <- OpResp{Error: err}
+		return
+	}
+
 	var result OpResp
 
 	switch req.opType {
@@ -195,12 +197,7 @@ func (fr *FileRepo) processOperation(ctx context.Context, req opReq) {
 		result = OpResp{Error: fmt.Errorf("unknown operation type: %d", req.opType)}
 	}
 
-	// Send result back
-	select {
-	case req.resultCh <- result:
-	case <-ctx.Done():
-		// Context cancelled, don't block
-	}
+	req.resultCh <- result
 }
 
 // loadCacheSync loads all chats into memory cache
@@ -209,7 +206,7 @@ func (fr *FileRepo) loadCacheSync() error {
 	fr.mu.RLock()
 	defer fr.mu.RUnlock()
 
-	chats, err := loadChatFromFile(fr.dataFile)
+	chats, err := loadChatFromFile(fr.dataFile, fr.logger)
 	if err != nil {
 		fr.logger.Errorf("failed to load initial data: %v", err)
 		return fmt.Errorf("failed to load initial data: %w", err)
@@ -423,7 +420,7 @@ func (fr *FileRepo) updateChatInternal(chat *Chat) (*Chat, error) {
 	fr.cacheMu.Lock()
 	if _, exists := fr.cache[chat.ID]; !exists {
 		fr.cacheMu.Unlock()
-		log.Errorf("chat with id %s not found", chat.ID)
+		fr.logger.Errorf("chat with id %s not found", chat.ID)
 		return nil, fmt.Errorf("chat with id %s not found", chat.ID)
 	}
 
@@ -480,140 +477,63 @@ func (fr *FileRepo) deleteChatInternal(chatID string) (bool, error) {
 	return true, nil
 }
 
-// ListChatsAsync lists all chats from cache
-func (fr *FileRepo) ListChatsAsync(
-	ctx context.Context,
-	keyword, model, provider *string,
-	limit int,
-) <-chan OpResp {
+// enqueue sends the operation to the workers. If the queue is full, it blocks until a worker
+// takes an operation or ctx is done (back-pressure). The result is sent to the returned channel.
+func (fr *FileRepo) enqueue(ctx context.Context, op opType, data any) <-chan OpResp {
 	resultCh := make(chan OpResp, 1)
 
 	// NOTE: Check if repository is shutdown
 	fr.shutdownMu.RLock()
+	defer fr.shutdownMu.RUnlock()
 	if fr.isShutdown {
-		fr.shutdownMu.RUnlock()
-
-		go func() { resultCh <- OpResp{Error: errors.New("repository is shutdown")} }()
-
+		resultCh <- OpResp{Error: ErrRepoClosed}
+		return resultCh
+	}
+	if err := ctx.Err(); err != nil {
+		resultCh <- OpResp{Error: err}
 		return resultCh
 	}
-	fr.shutdownMu.RUnlock()
 
-	// NOTE: Send operation request to operation queue
+	req := opReq{ctx: ctx, opType: op, data: data, resultCh: resultCh}
 	select {
-	case fr.opCh <- opReq{
-		opType:   opListChats,
-		data:     ListChatsOption{keyword: keyword, model: model, provider: provider, limit: limit},
-		resultCh: resultCh,
-	}:
-		fr.logger.Info("list chats operation enqueued")
+	case fr.opCh <- req:
+		return resultCh
+	default:
+		fr.logger.Warnf("operation queue is full (%d), waiting for a worker", cap(fr.opCh))
+	}
 
+	select {
+	case fr.opCh <- req:
 	case <-ctx.Done():
-		go func() {
-			resultCh <- OpResp{Error: ctx.Err()}
-		}()
+		resultCh <- OpResp{Error: ctx.Err()}
 	}
 
 	return resultCh
 }
 
+// ListChatsAsync lists all chats from cache
+func (fr *FileRepo) ListChatsAsync(
+	ctx context.Context,
+	keyword, model, provider *string,
+	limit int,
+) <-chan OpResp {
+	return fr.enqueue(ctx, opListChats,
+		ListChatsOption{keyword: keyword, model: model, provider: provider, limit: limit})
+}
+
 // GetChatAsync returns a chat from cache
 func (fr *FileRepo) GetChatAsync(ctx context.Context, chatID string) <-chan OpResp {
-	resultCh := make(chan OpResp, 1)
-
-	// NOTE: Check if repository is shutdown
-	fr.shutdownMu.RLock()
-	if fr.isShutdown {
-		fr.shutdownMu.RUnlock()
-		go func() {
-			resultCh <- OpResp{Error: errors.New("repository is shutdown")}
-		}()
-		return resultCh
-	}
-	fr.shutdownMu.RUnlock()
-
-	// NOTE: Send operation request to operation queue
-	select {
-	case fr.opCh <- opReq{
-		opType:   opGetChat,
-		data:     chatID,
-		resultCh: resultCh,
-	}:
-		fr.logger.Info("get chat operation enqueued")
-
-	case <-ctx.Done():
-		go func() {
-			resultCh <- OpResp{Error: ctx.Err()}
-		}()
-	}
-
-	return resultCh
+	return fr.enqueue(ctx, opGetChat, chatID)
 }
 
 // AddChatAsync adds a chat to cache
 func (fr *FileRepo) AddChatAsync(ctx context.Context, chat *Chat) <-chan OpResp {
-	resultCh := make(chan OpResp, 1)
-
-	// NOTE: Check if repository is shutdown
-	fr.shutdownMu.RLock()
-	if fr.isShutdown {
-		fr.shutdownMu.RUnlock()
-		go func() {
-			resultCh <- OpResp{Error: errors.New("repository is shutdown")}
-		}()
-		return resultCh
-	}
-	fr.shutdownMu.RUnlock()
-
-	// NOTE: Send operation request to operation queue
-	select {
-	case fr.opCh <- opReq{
-		opType:   opAddChat,
-		data:     chat,
-		resultCh: resultCh,
-	}:
-		fr.logger.Info("add chat operation enqueued")
-
-	case <-ctx.Done():
-		go func() {
-			resultCh <- OpResp{Error: ctx.Err()}
-		}()
-	}
-
-	return resultCh
+	return fr.enqueue(ctx, opAddChat, chat)
 }
 
 // UpdateChatAsync updates a chat in cache
 func (fr *FileRepo) UpdateChatAsync(ctx context.Context, chat *Chat) <-chan OpResp {
-	resultCh := make(chan OpResp, 1)
-
-	// NOTE: Check if repository is shutdown
-	fr.shutdownMu.RLock()
-	if fr.isShutdown {
-		fr.shutdownMu.RUnlock()
-		go func() {
-			resultCh <- OpResp{Error: errors.New("repository is shutdown")}
-		}()
-		return resultCh
-	}
-	fr.shutdownMu.RUnlock()
-
-	// NOTE: Send operation request to operation queue
-	select {
-	case fr.opCh <- opReq{
-		opType:   opUpdateChat,
-		data:     chat,
-		resultCh: resultCh,
-	}:
-		fr.logger.Info("update chat operation enqueued")
-
-	case <-ctx.Done():
-		go func() {
-			resultCh <- OpResp{Error: ctx.Err()}
-		}()
-	}
-
-	return resultCh
+	return fr.enqueue(ctx, opUpdateChat, chat)
 }
 
 // DeleteChatAsync deletes a chat from cache
@@ -621,35 +541,7 @@ func (fr *FileRepo) DeleteChatAsync(
 	ctx context.Context,
 	chatID string,
 ) <-chan OpResp {
-	resultCh := make(chan OpResp, 1)
-
-	// NOTE: Check if repository is shutdown
-	fr.shutdownMu.RLock()
-	if fr.isShutdown {
-		fr.shutdownMu.RUnlock()
-		go func() {
-			resultCh <- OpResp{Error: errors.New("repository is shutdown")}
-		}()
-		return resultCh
-	}
-	fr.shutdownMu.RUnlock()
-
-	// NOTE: Send operation request to operation queue
-	select {
-	case fr.opCh <- opReq{
-		opType:   opDeleteChat,
-		data:     chatID,
-		resultCh: resultCh,
-	}:
-		fr.logger.Info("delete chat operation enqueued")
-
-	case <-ctx.Done():
-		go func() {
-			resultCh <- OpResp{Error: ctx.Err()}
-		}()
-	}
-
-	return resultCh
+	return fr.enqueue(ctx, opDeleteChat, chatID)
 }
 
 // ListChatsAsync lists chats from cache
@@ -758,7 +650,8 @@ func (fr *FileRepo) DeleteChat(ctx context.Context, chatID string) (bool, error)
 	}
 }
 
-// Close shuts down the repository gracefully
+// Close shuts down the repository gracefully: the new operations are rejected with ErrRepoClosed,
+// the queued operations are processed before it returns
 func (fr *FileRepo) Close() error {
 	fr.shutdownMu.Lock()
 	if fr.isShutdown {
@@ -769,68 +662,26 @@ func (fr *FileRepo) Close() error {
 		return nil
 	}
 	fr.isShutdown = true
-	fr.shutdownMu.Unlock()
 
-	// Signal all workers to shutdown
-	close(fr.shutdownCh)
+	// NOTE: No sender holds the read lock any more, the workers drain the queue and exit
+	close(fr.opCh)
+	fr.shutdownMu.Unlock()
 
 	// Wait for all workers to finish
 	fr.workerWg.Wait()
 
-	// Close operation channel
-	close(fr.opCh)
-
 	fr.logger.Info("Repository closed gracefully")
 	return nil
 }
 
-// loadChatFromFile loads chat data from a file
-func loadChatFromFile(file string) ([]*Chat, error) {
-	f, err := os.Open(file) //nolint:gosec
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	scanner := bufio.NewScanner(f)
-	chats := make([]*Chat, 0, 128)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		dat := &Chat{}
-		if err := sonic.UnmarshalString(line, dat); err != nil {
-			continue // skip invalid lines
-		}
-
-		chats = append(chats, dat)
-	}
-
-	return chats, scanner.Err()
+// loadChatFromFile loads chat data from a file, recovering it from the backup if it fails to parse
+func loadChatFromFile(file string, logger log.Logger) ([]*Chat, error) {
+	return loadJSONL[Chat](file, logger)
 }
 
-// persistChatToFile writes chat data to a file
+// persistChatToFile writes chat data to a file atomically, keeping the previous version as backup
 func persistChatToFile(file string, chats []*Chat) error {
-	f, err := os.Create(file) //nolint:gosec
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	for _, chat := range chats {
-		data, err := sonic.Marshal(chat)
-		if err != nil {
-			return err
-		}
-
-		if _, err := f.Write(append(data, '\n')); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return writeJSONL(file, chats)
 }
 
 // ExpandUser expands the ~ in the beginning of a file path to the user's home directory
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -429,6 +430,119 @@ func TestFileRepo_AsyncOperations(t *testing.T) {
 	}
 }
 
+func TestFileRepo_AsyncAndSyncReturnData(t *testing.T) {
+	dataFile := createTempFile(t)
+	repo, err := NewChatFileRepository(dataFile, 2, &discardLogger{})
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	chat := createTestChat("test-data-1")
+
+	if result := <-repo.AddChatAsync(ctx, chat); result.Error != nil || result.Data.(*Chat).ID != chat.ID {
+		t.Fatalf("AddChatAsync() = %+v, want the added chat", result)
+	}
+
+	result := <-repo.GetChatAsync(ctx, chat.ID)
+	got, _ := result.Data.(*Chat)
+	if result.Error != nil || got == nil || got.Messages[0].Content != "Test message for chat test-data-1" {
+		t.Errorf("GetChatAsync() = %+v, want the chat with its messages", result)
+	}
+
+	result = <-repo.ListChatsAsync(ctx, nil, nil, nil, 10)
+	if chats, _ := result.Data.([]*Chat); result.Error != nil || len(chats) != 1 || chats[0].ID != chat.ID {
+		t.Errorf("ListChatsAsync() = %+v, want the chat", result)
+	}
+
+	updated := createTestChat(chat.ID)
+	updated.Messages = append(updated.Messages, &Message{Role: RoleAssistant, Content: "answer"})
+	if got, err := repo.UpdateChat(ctx, updated); err != nil || len(got.Messages) != 2 {
+		t.Errorf("UpdateChat() = %+v, %v, want the updated chat", got, err)
+	}
+	if got, err := repo.Chat(ctx, chat.ID); err != nil || got == nil || len(got.Messages) != 2 {
+		t.Errorf("Chat() = %+v, %v, want the updated chat", got, err)
+	}
+
+	if result := <-repo.DeleteChatAsync(ctx, chat.ID); result.Error != nil || result.Data != true {
+		t.Errorf("DeleteChatAsync() = %+v, want true", result)
+	}
+	if got, err := repo.Chat(ctx, chat.ID); err != nil || got != nil {
+		t.Errorf("Chat() after delete = %+v, %v, want nil", got, err)
+	}
+}
+
+func TestFileRepo_BackPressure(t *testing.T) {
+	dataFile := createTempFile(t)
+	repo, err := NewChatFileRepository(dataFile, 1, &discardLogger{})
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	// NOTE: Block the only worker on the cache, then fill the queue
+	repo.cacheMu.Lock()
+	ctx := context.Background()
+	results := make([]<-chan OpResp, 0, DefaultOperationQueueSize+1)
+	for range DefaultOperationQueueSize + 1 {
+		results = append(results, repo.GetChatAsync(ctx, "missing"))
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if result := <-repo.GetChatAsync(timeoutCtx, "missing"); !errors.Is(result.Error, context.DeadlineExceeded) {
+		t.Errorf("GetChatAsync() on a full queue = %+v, want context.DeadlineExceeded", result)
+	}
+
+	repo.cacheMu.Unlock()
+	for i, resultCh := range results {
+		select {
+		case result := <-resultCh:
+			if result.Error != nil {
+				t.Errorf("operation %d error = %v", i, result.Error)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("operation %d timed out", i)
+		}
+	}
+}
+
+func TestFileRepo_CloseDrainsQueue(t *testing.T) {
+	dataFile := createTempFile(t)
+	repo, err := NewChatFileRepository(dataFile, 1, &discardLogger{})
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	ctx := context.Background()
+	results := make([]<-chan OpResp, 0, 20)
+	for i := range 20 {
+		results = append(results, repo.AddChatAsync(ctx, createTestChat(fmt.Sprintf("drain-%d", i))))
+	}
+
+	if err := repo.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// NOTE: Every queued operation is processed before Close returns
+	for i, resultCh := range results {
+		select {
+		case result := <-resultCh:
+			if result.Error != nil {
+				t.Errorf("operation %d error = %v", i, result.Error)
+			}
+		default:
+			t.Errorf("operation %d not processed before Close returned", i)
+		}
+	}
+
+	chats, err := loadChatFromFile(dataFile, &discardLogger{})
+	if err != nil || len(chats) != 20 {
+		t.Errorf("chats persisted = %d, %v, want 20", len(chats), err)
+	}
+}
+
 func TestFileRepo_ContextCancellation(t *testing.T) {
 	dataFile := createTempFile(t)
 	repo, err := NewChatFileRepository(dataFile, 2, &discardLogger{})
@@ -582,7 +696,7 @@ func TestLoadChatFromFile(t *testing.T) {
 	}
 
 	// Test loading
-	loadedChats, err := loadChatFromFile(tmpFile)
+	loadedChats, err := loadChatFromFile(tmpFile, &discardLogger{})
 	if err != nil {
 		t.Errorf("loadChatFromFile() error = %v", err)
 	}
@@ -592,7 +706,7 @@ func TestLoadChatFromFile(t *testing.T) {
 	}
 
 	// Test loading non-existent file
-	_, err = loadChatFromFile("/non/existent/file")
+	_, err = loadChatFromFile("/non/existent/file", &discardLogger{})
 	if err == nil {
 		t.Errorf("loadChatFromFile() should return error for non-existent file")
 	}
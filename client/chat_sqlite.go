@@ -0,0 +1,540 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/kydenul/log"
+	_ "modernc.org/sqlite" // Pure Go SQLite driver
+)
+
+const (
+	// SQLiteBusyTimeout is the time to wait for the lock of the database held by another k-cli
+	SQLiteBusyTimeout = 5 * time.Second
+
+	// sqliteQueryBatchSize is the maximum number of chats whose messages are loaded in one query
+	sqliteQueryBatchSize = 500
+)
+
+// sqliteSchema creates the tables and the indexes, the whole chat and message are kept as JSON in data,
+// the other columns are extracted from them for the filters and the order
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS chats (
+	id          TEXT PRIMARY KEY,
+	create_time INTEGER NOT NULL,
+	update_time INTEGER NOT NULL,
+	data        TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_chats_create_time ON chats (create_time);
+CREATE INDEX IF NOT EXISTS idx_chats_update_time ON chats (update_time);
+
+CREATE TABLE IF NOT EXISTS messages (
+	chat_id   TEXT NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+	seq       INTEGER NOT NULL,
+	role      TEXT NOT NULL,
+	model     TEXT NOT NULL DEFAULT '',
+	provider  TEXT NOT NULL DEFAULT '',
+	content   TEXT NOT NULL DEFAULT '',
+	timestamp INTEGER NOT NULL DEFAULT 0,
+	data      TEXT NOT NULL,
+	PRIMARY KEY (chat_id, seq)
+);
+CREATE INDEX IF NOT EXISTS idx_messages_timestamp ON messages (timestamp);
+CREATE INDEX IF NOT EXISTS idx_messages_model ON messages (model);
+CREATE INDEX IF NOT EXISTS idx_messages_provider ON messages (provider);
+
+CREATE TABLE IF NOT EXISTS migrations (
+	name       TEXT PRIMARY KEY,
+	applied_at INTEGER NOT NULL
+);
+`
+
+var _ ChatRepo = (*SQLiteRepo)(nil)
+
+// SQLiteRepo implements ChatRepo with a SQLite database, each write only touches the rows of its chat.
+// The async operations run in their own goroutine, the database serializes them.
+type SQLiteRepo struct {
+	logger log.Logger
+
+	dbFile string
+	db     *sql.DB
+
+	closed   bool
+	closedMu sync.RWMutex
+	opWg     sync.WaitGroup // 进行中的异步操作，Close 时等待其完成
+}
+
+// NewChatSQLiteRepository opens the SQLite database, creating the file and the tables if needed
+func NewChatSQLiteRepository(dbFile string, logger log.Logger) (*SQLiteRepo, error) {
+	dbFile, err := ExpandUser(dbFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand user: %w", err)
+	}
+
+	if err := EnsureFileExistsSync(dbFile); err != nil {
+		return nil, fmt.Errorf("failed to ensure file exists: %w", err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(%d)",
+		dbFile, SQLiteBusyTimeout.Milliseconds())
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", dbFile, err)
+	}
+
+	// NOTE: SQLite has a single writer, one connection avoids SQLITE_BUSY between the goroutines
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create the tables of %s: %w", dbFile, err)
+	}
+
+	return &SQLiteRepo{
+		logger: logger,
+
+		dbFile: dbFile,
+		db:     db,
+	}, nil
+}
+
+// MigrateFromJSONL imports the chats of chats.jsonl once, the chats already in the database are kept.
+// The file is left untouched, it returns the number of chats imported, 0 if already migrated.
+func (r *SQLiteRepo) MigrateFromJSONL(ctx context.Context, jsonl string) (int, error) {
+	jsonl, err := ExpandUser(jsonl)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expand user: %w", err)
+	}
+
+	var applied int
+	name := "jsonl:" + jsonl
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM migrations WHERE name = ?`, name).Scan(&applied); err != nil {
+		return 0, fmt.Errorf("failed to check migration: %w", err)
+	}
+	if applied > 0 {
+		return 0, nil
+	}
+
+	chats, err := loadChatFromFile(jsonl, r.logger)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("failed to load %s: %w", jsonl, err)
+	}
+
+	imported := 0
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		for _, chat := range chats {
+			var exists int
+			if err := tx.QueryRowContext(ctx,
+				`SELECT COUNT(*) FROM chats WHERE id = ?`, chat.ID).Scan(&exists); err != nil {
+				return err
+			}
+			if exists > 0 {
+				continue
+			}
+
+			if err := insertChat(ctx, tx, chat); err != nil {
+				return fmt.Errorf("failed to import chat %s: %w", chat.ID, err)
+			}
+			imported++
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO migrations (name, applied_at) VALUES (?, ?)`, name, time.Now().UnixMilli())
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to migrate %s: %w", jsonl, err)
+	}
+
+	r.logger.Infof("migrated %d chats from %s to %s", imported, jsonl, r.dbFile)
+
+	return imported, nil
+}
+
+// ListChats lists the chats by create time in descending order. A chat matches if one of its messages
+// matches all of keyword, model and provider, compared case-insensitively.
+func (r *SQLiteRepo) ListChats(
+	ctx context.Context,
+	keyword, model, provider *string,
+	limit int,
+) ([]*Chat, error) {
+	if err := r.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT data FROM chats`
+	args := make([]any, 0, 4)
+	if keyword != nil || model != nil || provider != nil {
+		// NOTE: Same filters as FileRepo.filterChatsByKeyword
+		conditions := []string{"m.chat_id = chats.id"}
+		if keyword != nil {
+			conditions = append(conditions, "instr(lower(m.content), lower(?)) > 0")
+			args = append(args, *keyword)
+		}
+		if model != nil {
+			conditions = append(conditions, "m.model != '' AND instr(lower(m.model), lower(?)) > 0")
+			args = append(args, *model)
+		}
+		if provider != nil {
+			conditions = append(conditions, "m.provider != '' AND instr(lower(m.provider), lower(?)) > 0")
+			args = append(args, *provider)
+		}
+		query += ` WHERE EXISTS (SELECT 1 FROM messages m WHERE ` + strings.Join(conditions, " AND ") + `)`
+	}
+	query += ` ORDER BY create_time DESC LIMIT ?`
+	args = append(args, max(limit, 0))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chats: %w", err)
+	}
+	defer rows.Close()
+
+	chats := make([]*Chat, 0)
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to list chats: %w", err)
+		}
+
+		chat := &Chat{}
+		if err := sonic.UnmarshalString(data, chat); err != nil {
+			r.logger.Warnf("skipping invalid chat: %v", err)
+			continue
+		}
+		chats = append(chats, chat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list chats: %w", err)
+	}
+
+	if err := r.loadMessages(ctx, chats); err != nil {
+		return nil, err
+	}
+
+	return chats, nil
+}
+
+// Chat returns the chat with its messages, nil if not found
+func (r *SQLiteRepo) Chat(ctx context.Context, chatID string) (*Chat, error) {
+	if err := r.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	var data string
+	err := r.db.QueryRowContext(ctx, `SELECT data FROM chats WHERE id = ?`, chatID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat %s: %w", chatID, err)
+	}
+
+	chat := &Chat{}
+	if err := sonic.UnmarshalString(data, chat); err != nil {
+		return nil, fmt.Errorf("failed to parse chat %s: %w", chatID, err)
+	}
+	if err := r.loadMessages(ctx, []*Chat{chat}); err != nil {
+		return nil, err
+	}
+
+	return chat, nil
+}
+
+// AddChat adds the chat, replacing the chat with the same ID
+func (r *SQLiteRepo) AddChat(ctx context.Context, chat *Chat) (*Chat, error) {
+	if err := r.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM chats WHERE id = ?`, chat.ID); err != nil {
+			return err
+		}
+		return insertChat(ctx, tx, chat)
+	})
+	if err != nil {
+		r.logger.Errorf("failed to add chat %s: %v", chat.ID, err)
+		return nil, fmt.Errorf("failed to add chat %s: %w", chat.ID, err)
+	}
+
+	r.logger.Infof("added chat: %s", chat.ID)
+
+	return chat, nil
+}
+
+// UpdateChat replaces the chat and its messages, the chat must exist
+func (r *SQLiteRepo) UpdateChat(ctx context.Context, chat *Chat) (*Chat, error) {
+	if err := r.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM chats WHERE id = ?`, chat.ID)
+		if err != nil {
+			return err
+		}
+		if deleted, err := result.RowsAffected(); err != nil {
+			return err
+		} else if deleted == 0 {
+			return fmt.Errorf("chat with id %s not found", chat.ID)
+		}
+
+		return insertChat(ctx, tx, chat)
+	})
+	if err != nil {
+		r.logger.Errorf("failed to update chat %s: %v", chat.ID, err)
+		return nil, fmt.Errorf("failed to update chat %s: %w", chat.ID, err)
+	}
+
+	r.logger.Infof("updated chat: %s", chat.ID)
+
+	return chat, nil
+}
+
+// DeleteChat deletes the chat and its messages, false if not found
+func (r *SQLiteRepo) DeleteChat(ctx context.Context, chatID string) (bool, error) {
+	if err := r.checkClosed(); err != nil {
+		return false, err
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM chats WHERE id = ?`, chatID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete chat %s: %w", chatID, err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete chat %s: %w", chatID, err)
+	}
+	if deleted == 0 {
+		r.logger.Warnf("chat with id %s not found", chatID)
+		return false, nil
+	}
+
+	r.logger.Infof("deleted chat: %s", chatID)
+
+	return true, nil
+}
+
+// ListChatsAsync lists the chats in a goroutine, see ListChats
+func (r *SQLiteRepo) ListChatsAsync(
+	ctx context.Context,
+	keyword, model, provider *string,
+	limit int,
+) <-chan OpResp {
+	return r.async(func() (any, error) { return r.ListChats(ctx, keyword, model, provider, limit) })
+}
+
+// GetChatAsync returns the chat in a goroutine, see Chat
+func (r *SQLiteRepo) GetChatAsync(ctx context.Context, chatID string) <-chan OpResp {
+	return r.async(func() (any, error) { return r.Chat(ctx, chatID) })
+}
+
+// AddChatAsync adds the chat in a goroutine, see AddChat
+func (r *SQLiteRepo) AddChatAsync(ctx context.Context, chat *Chat) <-chan OpResp {
+	return r.async(func() (any, error) { return r.AddChat(ctx, chat) })
+}
+
+// UpdateChatAsync updates the chat in a goroutine, see UpdateChat
+func (r *SQLiteRepo) UpdateChatAsync(ctx context.Context, chat *Chat) <-chan OpResp {
+	return r.async(func() (any, error) { return r.UpdateChat(ctx, chat) })
+}
+
+// DeleteChatAsync deletes the chat in a goroutine, see DeleteChat
+func (r *SQLiteRepo) DeleteChatAsync(ctx context.Context, chatID string) <-chan OpResp {
+	return r.async(func() (any, error) { return r.DeleteChat(ctx, chatID) })
+}
+
+// Close waits for the async operations in flight and closes the database
+func (r *SQLiteRepo) Close() error {
+	r.closedMu.Lock()
+	if r.closed {
+		r.closedMu.Unlock()
+
+		r.logger.Info("Repository already closed")
+
+		return nil
+	}
+	r.closed = true
+	r.closedMu.Unlock()
+
+	r.opWg.Wait()
+
+	if err := r.db.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", r.dbFile, err)
+	}
+
+	r.logger.Info("Repository closed gracefully")
+	return nil
+}
+
+// async runs the operation in a goroutine, unless the repository is closed
+func (r *SQLiteRepo) async(op func() (any, error)) <-chan OpResp {
+	resultCh := make(chan OpResp, 1)
+
+	r.closedMu.RLock()
+	defer r.closedMu.RUnlock()
+	if r.closed {
+		resultCh <- OpResp{Error: ErrRepoClosed}
+		return resultCh
+	}
+
+	r.opWg.Add(1)
+	go func() {
+		defer r.opWg.Done()
+
+		data, err := op()
+		resultCh <- OpResp{Data: data, Error: err}
+	}()
+
+	return resultCh
+}
+
+func (r *SQLiteRepo) checkClosed() error {
+	r.closedMu.RLock()
+	defer r.closedMu.RUnlock()
+
+	if r.closed {
+		return ErrRepoClosed
+	}
+
+	return nil
+}
+
+// withTx runs fn in a transaction, committed if fn succeeds
+func (r *SQLiteRepo) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// loadMessages loads the messages of the chats in order
+func (r *SQLiteRepo) loadMessages(ctx context.Context, chats []*Chat) error {
+	byID := make(map[string]*Chat, len(chats))
+	for _, chat := range chats {
+		chat.Messages = make([]*Message, 0)
+		byID[chat.ID] = chat
+	}
+
+	for start := 0; start < len(chats); start += sqliteQueryBatchSize {
+		batch := chats[start:min(start+sqliteQueryBatchSize, len(chats))]
+
+		args := make([]any, 0, len(batch))
+		for _, chat := range batch {
+			args = append(args, chat.ID)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")
+
+		rows, err := r.db.QueryContext(ctx, `SELECT chat_id, data FROM messages WHERE chat_id IN (`+
+			placeholders+`) ORDER BY chat_id, seq`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to load messages: %w", err)
+		}
+
+		for rows.Next() {
+			var chatID, data string
+			if err := rows.Scan(&chatID, &data); err != nil {
+				_ = rows.Close()
+				return fmt.Errorf("failed to load messages: %w", err)
+			}
+
+			msg := &Message{}
+			if err := sonic.UnmarshalString(data, msg); err != nil {
+				r.logger.Warnf("skipping invalid message of chat %s: %v", chatID, err)
+				continue
+			}
+			byID[chatID].Messages = append(byID[chatID].Messages, msg)
+		}
+
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return fmt.Errorf("failed to load messages: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// insertChat inserts the chat and its messages, the messages are stored apart from the chat
+func insertChat(ctx context.Context, tx *sql.Tx, chat *Chat) error {
+	header := *chat
+	header.Messages = nil
+
+	data, err := sonic.MarshalString(&header)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO chats (id, create_time, update_time, data) VALUES (?, ?, ?, ?)`,
+		chat.ID, chat.CreateTime.UnixNano(), chat.UpdateTime.UnixNano(), data); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO messages
+		(chat_id, seq, role, model, provider, content, timestamp, data) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for seq, msg := range chat.Messages {
+		data, err := sonic.MarshalString(msg)
+		if err != nil {
+			return err
+		}
+
+		if _, err := stmt.ExecContext(ctx, chat.ID, seq, msg.Role, msg.Model, msg.Provider,
+			messageText(msg), messageUnixMilli(msg), data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// messageText returns the text of the message searched by keyword, the text parts if the content is multipart
+func messageText(msg *Message) string {
+	switch content := msg.Content.(type) {
+	case string:
+		return content
+	case []any:
+		texts := make([]string, 0, len(content))
+		for _, part := range content {
+			if partMap, ok := part.(map[string]any); ok {
+				if text, exists := partMap["text"].(string); exists {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	default:
+		return ""
+	}
+}
+
+// messageUnixMilli returns the time of the message in milliseconds, 0 if unknown
+func messageUnixMilli(msg *Message) int64 {
+	if msg.Timestamp != nil && !msg.Timestamp.IsZero() {
+		return msg.Timestamp.UnixMilli()
+	}
+
+	return msg.UnixTimestamp
+}
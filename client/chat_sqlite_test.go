@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bytedance/sonic"
+)
+
+func newTestSQLiteRepo(t *testing.T, dbFile string) *SQLiteRepo {
+	t.Helper()
+
+	repo, err := NewChatSQLiteRepository(dbFile, &discardLogger{})
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	t.Cleanup(func() { _ = repo.Close() })
+
+	return repo
+}
+
+func TestSQLiteRepo_CRUD(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "chats.db")
+	repo := newTestSQLiteRepo(t, dbFile)
+	ctx := context.Background()
+
+	chat := createTestChat("test-sqlite-1")
+	chat.Messages = append(chat.Messages, &Message{
+		Role:      "assistant",
+		Content:   []any{map[string]any{"type": "text", "text": "Hello"}},
+		ToolCalls: []*ToolCall{{ID: "call_1", Type: "function", Function: ToolCallFunction{Name: "fetch"}}},
+		Usage:     &Usage{PromptTokens: 10, CompletionTokens: 5},
+	})
+
+	if _, err := repo.AddChat(ctx, chat); err != nil {
+		t.Fatalf("AddChat() error = %v", err)
+	}
+
+	got, err := repo.Chat(ctx, chat.ID)
+	if err != nil || got == nil {
+		t.Fatalf("Chat() = %v, %v", got, err)
+	}
+	want, _ := sonic.ConfigStd.MarshalToString(chat)
+	if data, _ := sonic.ConfigStd.MarshalToString(got); data != want {
+		t.Errorf("Chat() = %s, want %s", data, want)
+	}
+
+	// Update replaces the messages
+	chat.Messages = chat.Messages[:1]
+	chat.UpdateTime = time.Now()
+	if _, err := repo.UpdateChat(ctx, chat); err != nil {
+		t.Fatalf("UpdateChat() error = %v", err)
+	}
+	if got, _ := repo.Chat(ctx, chat.ID); got == nil || len(got.Messages) != 1 {
+		t.Errorf("Chat() after update = %+v, want 1 message", got)
+	}
+	if _, err := repo.UpdateChat(ctx, createTestChat("missing")); err == nil {
+		t.Error("UpdateChat() of a missing chat should fail")
+	}
+
+	// The chats survive reopening the database
+	if err := repo.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := repo.Chat(ctx, chat.ID); !errors.Is(err, ErrRepoClosed) {
+		t.Errorf("Chat() after Close() error = %v, want ErrRepoClosed", err)
+	}
+	if resp := <-repo.GetChatAsync(ctx, chat.ID); !errors.Is(resp.Error, ErrRepoClosed) {
+		t.Errorf("GetChatAsync() after Close() error = %v, want ErrRepoClosed", resp.Error)
+	}
+
+	repo = newTestSQLiteRepo(t, dbFile)
+	resp := <-repo.GetChatAsync(ctx, chat.ID)
+	if got, ok := resp.Data.(*Chat); resp.Error != nil || !ok || got == nil || len(got.Messages) != 1 {
+		t.Fatalf("GetChatAsync() after reopening = %+v", resp)
+	}
+
+	deleted, err := repo.DeleteChat(ctx, chat.ID)
+	if err != nil || !deleted {
+		t.Errorf("DeleteChat() = %v, %v, want true", deleted, err)
+	}
+	if deleted, _ := repo.DeleteChat(ctx, chat.ID); deleted {
+		t.Error("DeleteChat() of a deleted chat should return false")
+	}
+	if got, err := repo.Chat(ctx, chat.ID); err != nil || got != nil {
+		t.Errorf("Chat() after delete = %v, %v, want nil", got, err)
+	}
+}
+
+func TestSQLiteRepo_ListChats(t *testing.T) {
+	repo := newTestSQLiteRepo(t, filepath.Join(t.TempDir(), "chats.db"))
+	ctx := context.Background()
+
+	base := time.Now()
+	for i, msg := range []*Message{
+		{Role: "user", Content: "Weather in Shanghai", Model: "deepseek-chat", Provider: "OpenAI"},
+		{Role: "user", Content: "Review this diff", Model: "llama3.1", Provider: "Ollama"},
+		{Role: "user", Content: []any{map[string]any{"type": "text", "text": "shanghai food"}}, Model: "gpt-4o"},
+	} {
+		chat := &Chat{
+			ID:         string(rune('a' + i)),
+			CreateTime: base.Add(time.Duration(i) * time.Minute),
+			UpdateTime: base,
+			Messages:   []*Message{msg},
+		}
+		if _, err := repo.AddChat(ctx, chat); err != nil {
+			t.Fatalf("AddChat() error = %v", err)
+		}
+	}
+
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name                     string
+		keyword, model, provider *string
+		limit                    int
+		want                     []string
+	}{
+		{name: "all by create time", limit: 10, want: []string{"c", "b", "a"}},
+		{name: "limit", limit: 2, want: []string{"c", "b"}},
+		{name: "keyword is case-insensitive", keyword: str("SHANGHAI"), limit: 10, want: []string{"c", "a"}},
+		{name: "model", model: str("LLAMA"), limit: 10, want: []string{"b"}},
+		{name: "provider excludes empty", provider: str("o"), limit: 10, want: []string{"b", "a"}},
+		{name: "all filters", keyword: str("weather"), model: str("deepseek"), provider: str("openai"), limit: 10,
+			want: []string{"a"}},
+		{name: "filters on one message", keyword: str("weather"), model: str("llama"), limit: 10, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chats, err := repo.ListChats(ctx, tt.keyword, tt.model, tt.provider, tt.limit)
+			if err != nil {
+				t.Fatalf("ListChats() error = %v", err)
+			}
+
+			ids := make([]string, 0, len(chats))
+			for _, chat := range chats {
+				if len(chat.Messages) != 1 {
+					t.Errorf("chat %s has %d messages, want 1", chat.ID, len(chat.Messages))
+				}
+				ids = append(ids, chat.ID)
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("ListChats() = %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("ListChats() = %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLiteRepo_MigrateFromJSONL(t *testing.T) {
+	dir := t.TempDir()
+	jsonl := filepath.Join(dir, "chats.jsonl")
+
+	lines := ""
+	for _, id := range []string{"m1", "m2"} {
+		data, _ := sonic.MarshalString(createTestChat(id))
+		lines += data + "\n"
+	}
+	lines += "not json\n"
+	if err := os.WriteFile(jsonl, []byte(lines), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newTestSQLiteRepo(t, filepath.Join(dir, "chats.db"))
+	ctx := context.Background()
+
+	// A chat already in the database is kept
+	existing := createTestChat("m1")
+	existing.Messages[0].Content = "kept"
+	if _, err := repo.AddChat(ctx, existing); err != nil {
+		t.Fatalf("AddChat() error = %v", err)
+	}
+
+	imported, err := repo.MigrateFromJSONL(ctx, jsonl)
+	if err != nil || imported != 1 {
+		t.Fatalf("MigrateFromJSONL() = %d, %v, want 1", imported, err)
+	}
+	if chat, _ := repo.Chat(ctx, "m1"); chat == nil || chat.Messages[0].Content != "kept" {
+		t.Errorf("MigrateFromJSONL() replaced the existing chat: %+v", chat)
+	}
+	if chat, _ := repo.Chat(ctx, "m2"); chat == nil || len(chat.Messages) != 1 {
+		t.Errorf("MigrateFromJSONL() did not import m2: %+v", chat)
+	}
+
+	// The migration runs once, a deleted chat is not imported again
+	if _, err := repo.DeleteChat(ctx, "m2"); err != nil {
+		t.Fatal(err)
+	}
+	if imported, err := repo.MigrateFromJSONL(ctx, jsonl); err != nil || imported != 0 {
+		t.Errorf("second MigrateFromJSONL() = %d, %v, want 0", imported, err)
+	}
+
+	if data, _ := os.ReadFile(jsonl); string(data) != lines {
+		t.Error("MigrateFromJSONL() modified chats.jsonl")
+	}
+
+	// A missing chats.jsonl is not an error
+	if imported, err := repo.MigrateFromJSONL(ctx, filepath.Join(dir, "missing.jsonl")); err != nil || imported != 0 {
+		t.Errorf("MigrateFromJSONL() of a missing file = %d, %v, want 0", imported, err)
+	}
+}
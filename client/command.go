@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	CommandPrefix = "/"
+
+	DefaultListChatsLimit = 20
+	// DefaultUsageDays is the number of recent days reported by /usage
+	DefaultUsageDays = 7
+)
+
+// ErrExit is returned by a command handler to ask the interactive session to quit
+var ErrExit = errors.New("exit")
+
+// CommandHandler handles a slash command. args holds the whitespace separated arguments
+// after the command name, and out is where the command writes its output.
+type CommandHandler func(ctx context.Context, mgr *Manager, args []string, out io.Writer) error
+
+// Command is a slash command available inside an interactive session, e.g. `/list weather`
+type Command struct {
+	Name        string // Command name without the leading slash
+	Usage       string // Argument synopsis, e.g. "<chat-id>"
+	Description string
+
+	Handler CommandHandler
+}
+
+// CommandRegistry holds the slash commands of an interactive session
+type CommandRegistry struct {
+	mu       sync.RWMutex
+	commands map[string]*Command
+}
+
+// NewCommandRegistry returns a registry with the builtin commands registered
+func NewCommandRegistry() *CommandRegistry {
+	registry := &CommandRegistry{
+		commands: make(map[string]*Command),
+	}
+
+	for _, cmd := range builtinCommands(registry) {
+		registry.commands[cmd.Name] = cmd
+	}
+
+	return registry
+}
+
+// Register adds a command to the registry, replacing any command with the same name
+func (r *CommandRegistry) Register(cmd *Command) error {
+	if cmd == nil || cmd.Name == "" || cmd.Handler == nil {
+		return errors.New("command name or handler is empty")
+	}
+
+	name := strings.TrimPrefix(cmd.Name, CommandPrefix)
+	if strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("command name '%s' must not contain whitespace", cmd.Name)
+	}
+	cmd.Name = name
+
+	r.mu.Lock()
+	r.commands[name] = cmd
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Unregister removes the command with the specified name
+func (r *CommandRegistry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.commands, strings.TrimPrefix(name, CommandPrefix))
+	r.mu.Unlock()
+}
+
+// Command returns the command with the specified name, otherwise return nil
+func (r *CommandRegistry) Command(name string) *Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.commands[strings.TrimPrefix(name, CommandPrefix)]
+}
+
+// Commands returns all registered commands sorted by name
+func (r *CommandRegistry) Commands() []*Command {
+	r.mu.RLock()
+	cmds := make([]*Command, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		cmds = append(cmds, cmd)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+
+	return cmds
+}
+
+// Execute runs the slash command in line. It returns ErrExit if the command asks to quit.
+func (r *CommandRegistry) Execute(ctx context.Context, mgr *Manager, line string, out io.Writer) error {
+	name, args := ParseCommand(line)
+	if name == "" {
+		return fmt.Errorf("'%s' is not a command", line)
+	}
+
+	cmd := r.Command(name)
+	if cmd == nil {
+		return fmt.Errorf("unknown command '%s%s', type %shelp for a list of commands",
+			CommandPrefix, name, CommandPrefix)
+	}
+
+	return cmd.Handler(ctx, mgr, args, out)
+}
+
+// IsCommand reports whether the user input is a slash command: its first word is a registered command,
+// or it is a single word without a path separator, e.g. the mistyped "/hepl", reported as an unknown command.
+// Any other input is a message to the model, even if it starts with the prefix, e.g. "/etc/hosts is broken".
+func (r *CommandRegistry) IsCommand(input string) bool {
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, CommandPrefix) {
+		return false
+	}
+
+	name, args := ParseCommand(input)
+	if name != "" && r.Command(name) != nil {
+		return true
+	}
+
+	return len(args) == 0 && !strings.Contains(strings.TrimPrefix(input, CommandPrefix), "/")
+}
+
+// ParseCommand splits a slash command into its name and arguments
+func ParseCommand(input string) (string, []string) {
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, CommandPrefix) {
+		return "", nil
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(input, CommandPrefix))
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	return fields[0], fields[1:]
+}
@@ -0,0 +1,310 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// builtinCommands returns the commands every interactive session starts with
+func builtinCommands(registry *CommandRegistry) []*Command {
+	return []*Command{
+		{
+			Name:        "help",
+			Description: "Show available commands",
+			Handler:     helpHandler(registry),
+		},
+		{
+			Name:        "new",
+			Description: "Start a new chat",
+			Handler:     newChatHandler,
+		},
+		{
+			Name:        "load",
+			Usage:       "<chat-id>",
+			Description: "Continue an existing chat",
+			Handler:     loadChatHandler,
+		},
+		{
+			Name:        "list",
+			Usage:       "[keyword]",
+			Description: "List recent chats, optionally filtered by keyword",
+			Handler:     listChatsHandler,
+		},
+		{
+			Name:        "delete",
+			Usage:       "[chat-id]",
+			Description: "Delete a chat, the current chat by default",
+			Handler:     deleteChatHandler,
+		},
+		{
+			Name:        "model",
+			Usage:       "[name]",
+			Description: "Show or switch the model",
+			Handler:     modelHandler,
+		},
+		{
+			Name:        "profile",
+			Usage:       "[name]",
+			Description: "List profiles or switch the provider and model to a profile",
+			Handler:     profileHandler,
+		},
+		{
+			Name:        "prompt",
+			Usage:       "[name]",
+			Description: "List prompts or switch the prompt used in the system prompt",
+			Handler:     promptHandler,
+		},
+		{
+			Name:        "mcp",
+			Usage:       "list | tools <server>",
+			Description: "List connected MCP servers or the tools of a server",
+			Handler:     mcpHandler,
+		},
+		{
+			Name:        "usage",
+			Usage:       "[days]",
+			Description: "Show the token usage and cost of the current chat and of the recent days",
+			Handler:     usageHandler,
+		},
+		{
+			Name:        "exit",
+			Description: "Quit the session",
+			Handler: func(context.Context, *Manager, []string, io.Writer) error {
+				return ErrExit
+			},
+		},
+	}
+}
+
+func helpHandler(registry *CommandRegistry) CommandHandler {
+	return func(_ context.Context, _ *Manager, _ []string, out io.Writer) error {
+		for _, cmd := range registry.Commands() {
+			synopsis := CommandPrefix + cmd.Name
+			if cmd.Usage != "" {
+				synopsis += " " + cmd.Usage
+			}
+
+			fmt.Fprintf(out, "  %-28s %s\n", synopsis, cmd.Description)
+		}
+
+		return nil
+	}
+}
+
+func newChatHandler(_ context.Context, mgr *Manager, _ []string, out io.Writer) error {
+	fmt.Fprintf(out, "New chat: %s\n", mgr.NewChat())
+	return nil
+}
+
+func loadChatHandler(ctx context.Context, mgr *Manager, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: /load <chat-id>")
+	}
+
+	chat, err := mgr.LoadChat(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Loaded chat %s with %d messages\n", chat.ID, len(chat.Messages))
+	for _, msg := range chat.Messages {
+		switch msg.Role {
+		case RoleUser:
+			fmt.Fprintf(out, "👤 User: %v\n\n", msg.Content)
+		case RoleAssistant:
+			fmt.Fprintf(out, "🤖 Assistant: %v\n\n", msg.Content)
+		}
+	}
+
+	return nil
+}
+
+func listChatsHandler(ctx context.Context, mgr *Manager, args []string, out io.Writer) error {
+	var keyword *string
+	if len(args) > 0 {
+		kw := strings.Join(args, " ")
+		keyword = &kw
+	}
+
+	chats, err := mgr.ChatSvr().ListChats(ctx, keyword, nil, nil, DefaultListChatsLimit)
+	if err != nil {
+		return fmt.Errorf("failed to list chats: %w", err)
+	}
+
+	if len(chats) == 0 {
+		fmt.Fprintln(out, "No chats found")
+		return nil
+	}
+
+	for _, chat := range chats {
+		marker := " "
+		if chat.ID == mgr.ChatID() {
+			marker = "*"
+		}
+
+		fmt.Fprintf(out, "%s %s  %s  %s\n",
+			marker, chat.ID, chat.UpdateTime.Format("2006-01-02 15:04"), chat.Title())
+	}
+
+	return nil
+}
+
+func deleteChatHandler(ctx context.Context, mgr *Manager, args []string, out io.Writer) error {
+	chatID := mgr.ChatID()
+	if len(args) > 0 {
+		chatID = args[0]
+	}
+
+	deleted, err := mgr.ChatSvr().DeleteChat(ctx, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to delete chat %s: %w", chatID, err)
+	}
+	if !deleted {
+		return fmt.Errorf("chat %s not found", chatID)
+	}
+
+	fmt.Fprintf(out, "Deleted chat %s\n", chatID)
+
+	// NOTE: The current chat is gone, start over
+	if chatID == mgr.ChatID() {
+		fmt.Fprintf(out, "New chat: %s\n", mgr.NewChat())
+	}
+
+	return nil
+}
+
+func modelHandler(_ context.Context, mgr *Manager, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprintf(out, "Current model: %s\n", mgr.Model())
+		return nil
+	}
+
+	mgr.SetModel(args[0])
+	fmt.Fprintf(out, "Switched model to %s\n", args[0])
+
+	return nil
+}
+
+func profileHandler(_ context.Context, mgr *Manager, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		profiles := mgr.Profiles()
+		if len(profiles) == 0 {
+			fmt.Fprintln(out, "No profiles configured")
+			return nil
+		}
+
+		for _, name := range profiles {
+			marker := " "
+			if name == mgr.Profile() {
+				marker = "*"
+			}
+
+			profile := mgr.config.Profiles[name]
+			fmt.Fprintf(out, "%s %-20s %s/%s\n", marker, name, profile.Provider, profile.Model)
+		}
+
+		return nil
+	}
+
+	if err := mgr.SetProfile(args[0]); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Switched profile to %s (%s)\n", mgr.Profile(), mgr.Model())
+
+	return nil
+}
+
+func promptHandler(_ context.Context, mgr *Manager, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		for _, prompt := range mgr.PromptSvr().AllPrompts() {
+			marker := " "
+			if prompt.Name == mgr.PromptName() {
+				marker = "*"
+			}
+
+			fmt.Fprintf(out, "%s %-20s %s\n", marker, prompt.Name, prompt.Description)
+		}
+
+		return nil
+	}
+
+	if err := mgr.SetPrompt(args[0]); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Switched prompt to %s\n", args[0])
+
+	return nil
+}
+
+func mcpHandler(ctx context.Context, mgr *Manager, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: /mcp list | /mcp tools <server>")
+	}
+
+	switch args[0] {
+	case "list":
+		svrs := mgr.MCPMgr.MCPServerList()
+		if len(svrs) == 0 {
+			fmt.Fprintln(out, "No connected MCP servers")
+			return nil
+		}
+
+		for _, name := range svrs {
+			fmt.Fprintf(out, "  %s\n", name)
+		}
+
+	case "tools":
+		if len(args) != 2 {
+			return errors.New("usage: /mcp tools <server>")
+		}
+
+		tools, err := mgr.MCPMgr.ToolsByServerName(ctx, args[1])
+		if err != nil {
+			return err
+		}
+
+		for _, tool := range tools {
+			fmt.Fprintf(out, "  %-28s %s\n", tool.Name, tool.Description)
+		}
+
+	default:
+		return fmt.Errorf("unknown subcommand '%s', usage: /mcp list | /mcp tools <server>", args[0])
+	}
+
+	return nil
+}
+
+func usageHandler(ctx context.Context, mgr *Manager, args []string, out io.Writer) error {
+	days := DefaultUsageDays
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return errors.New("usage: /usage [days]")
+		}
+		days = n
+	}
+
+	current, _ := SummarizeUsage([]*Chat{{ID: mgr.ChatID(), Messages: mgr.messages}}, mgr.config.Prices)
+	fmt.Fprintf(out, "Current chat: %s\n", current)
+
+	// NOTE: The usage of the recent days is summed over all chats
+	chats, err := mgr.ChatSvr().ListChats(ctx, nil, nil, nil, math.MaxInt)
+	if err != nil {
+		return fmt.Errorf("failed to list chats: %w", err)
+	}
+
+	_, daily := SummarizeUsage(chats, mgr.config.Prices)
+	if len(daily) > days {
+		daily = daily[len(daily)-days:]
+	}
+	for _, day := range daily {
+		fmt.Fprintf(out, "  %s  %s\n", day.Day, day)
+	}
+
+	return nil
+}
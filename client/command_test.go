@@ -0,0 +1,146 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expectedName string
+		expectedArgs []string
+	}{
+		{
+			name:         "command without args",
+			input:        "/new",
+			expectedName: "new",
+			expectedArgs: []string{},
+		},
+		{
+			name:         "command with args",
+			input:        "  /mcp tools  weather ",
+			expectedName: "mcp",
+			expectedArgs: []string{"tools", "weather"},
+		},
+		{
+			name:         "not a command",
+			input:        "hello /new",
+			expectedName: "",
+			expectedArgs: nil,
+		},
+		{
+			name:         "bare prefix",
+			input:        "/",
+			expectedName: "",
+			expectedArgs: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, args := ParseCommand(tt.input)
+			if name != tt.expectedName {
+				t.Errorf("ParseCommand() name = %q, want %q", name, tt.expectedName)
+			}
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("ParseCommand() args = %v, want %v", args, tt.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestCommandRegistry_IsCommand(t *testing.T) {
+	registry := NewCommandRegistry()
+
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"/help", true},
+		{"  /model gpt-4o  ", true},
+		{"/exit now", true},
+		{"/etc/hosts is broken, why?", false},
+		{"/* comment */ what does this do?", false},
+		{"/hepl", true},
+		{"/", true},
+		{"/etc/hosts", false},
+		{"/unknown command with args", false},
+		{"help", false},
+	}
+
+	for _, tt := range tests {
+		if got := registry.IsCommand(tt.input); got != tt.expected {
+			t.Errorf("IsCommand(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestCommandRegistry_Builtins(t *testing.T) {
+	registry := NewCommandRegistry()
+
+	for _, name := range []string{"help", "new", "load", "list", "delete", "model", "prompt", "mcp", "usage", "exit"} {
+		if registry.Command(name) == nil {
+			t.Errorf("builtin command /%s is not registered", name)
+		}
+	}
+
+	err := registry.Execute(context.Background(), nil, "/exit", io.Discard)
+	if !errors.Is(err, ErrExit) {
+		t.Errorf("Execute(/exit) error = %v, want ErrExit", err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := registry.Execute(context.Background(), nil, "/help", out); err != nil {
+		t.Fatalf("Execute(/help) error = %v", err)
+	}
+	if !strings.Contains(out.String(), "/load <chat-id>") {
+		t.Errorf("Execute(/help) output should contain usage, got %q", out.String())
+	}
+}
+
+func TestCommandRegistry_Register(t *testing.T) {
+	registry := NewCommandRegistry()
+
+	var gotArgs []string
+	err := registry.Register(&Command{
+		Name:        "/echo",
+		Description: "Echo arguments",
+		Handler: func(_ context.Context, _ *Manager, args []string, out io.Writer) error {
+			gotArgs = args
+			_, err := io.WriteString(out, strings.Join(args, " "))
+			return err
+		},
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := registry.Execute(context.Background(), nil, "/echo a b", out); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if out.String() != "a b" || !reflect.DeepEqual(gotArgs, []string{"a", "b"}) {
+		t.Errorf("Execute() output = %q, args = %v", out.String(), gotArgs)
+	}
+
+	// Invalid commands
+	if err := registry.Register(&Command{Name: "bad name", Handler: builtinCommands(registry)[0].Handler}); err == nil {
+		t.Errorf("Register() should reject names with whitespace")
+	}
+	if err := registry.Register(&Command{Name: "nohandler"}); err == nil {
+		t.Errorf("Register() should reject commands without handler")
+	}
+
+	// Unknown commands
+	registry.Unregister("echo")
+	if err := registry.Execute(context.Background(), nil, "/echo", out); err == nil {
+		t.Errorf("Execute() should fail for unregistered command")
+	}
+}
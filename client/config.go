@@ -3,12 +3,23 @@ package client
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/kydenul/log"
 	"github.com/spf13/viper"
 )
 
+// ErrUnknownProfile is returned when the profile is not in the profiles of client.yaml
+var ErrUnknownProfile = errors.New("unknown profile")
+
 var (
 	// DefaultCfgPath is the default configuration file path
 	DefaultCfgPath = filepath.Join(".", "config", "client.yaml")
@@ -30,6 +41,31 @@ const (
 	DefaultMaxTurns        = 10
 	DefaultMaxTokens       = 32768
 	DefaultReasoningEffort = "medium"
+	DefaultToolCallMode    = ToolCallModeNative
+
+	// StorageTypeFile stores the chats in chats.jsonl
+	StorageTypeFile = DefaultStorageType
+	// StorageTypeSQLite stores the chats in a SQLite database, chats.db
+	StorageTypeSQLite = "sqlite"
+
+	// ToolCallModeNative passes the MCP tools through native function calling if the provider supports it
+	ToolCallModeNative = "native"
+	// ToolCallModeXML describes the MCP tools in the system prompt and parses the XML tool use in the response
+	ToolCallModeXML = "xml"
+
+	// MaxTokensLimit is the maximum of max_tokens
+	MaxTokensLimit = 1 << 20
+)
+
+var (
+	// StorageTypes are the supported values of storage_type
+	StorageTypes = []string{StorageTypeFile, StorageTypeSQLite}
+
+	// ReasoningEfforts are the supported values of reasoning_effort
+	ReasoningEfforts = []string{"high", "medium", "low", "minimal"}
+
+	// ProvidersWithAPIKey are the providers requiring api_key, OpenAI on the local machine excepted
+	ProvidersWithAPIKey = []string{ProviderOpenAI, ProviderTaiji, ProviderAnthropic, ProviderGemini}
 )
 
 type Config struct {
@@ -38,12 +74,20 @@ type Config struct {
 
 	cfgPath string // 配置文件路径
 
+	root    *Config // 应用 profile 之前的配置
+	profile string  // 当前使用的 profile
+
+	apiKeyKey string    // API key 来源的 YAML 键，用于报告解析失败，如 profiles.<name>.api_key
+	apiKeys   *sync.Map // api_key_cmd 的输出，所有副本共享，每个命令只执行一次
+
 	// Model Provider
 	Provider      string `mapstructure:"provider"`
 	BaseURL       string `mapstructure:"base_url"`
 	CustomAPIPath string `mapstructure:"custom_api_path"`
 	Model         string `mapstructure:"model"`
 	APIKey        string `mapstructure:"api_key"`
+	APIKeyEnv     string `mapstructure:"api_key_env"` // 保存 API key 的环境变量，api_key 为空时使用
+	APIKeyCmd     string `mapstructure:"api_key_cmd"` // 输出 API key 的命令，api_key 与 api_key_env 均为空时使用
 
 	StorageType string `mapstructure:"storage_type,omitempty"`
 	// MCP
@@ -55,6 +99,155 @@ type Config struct {
 	MaxTokens       uint64 `mapstructure:"max_tokens"`       // 最大 token 数
 	ReasoningEffort string `mapstructure:"reasoning_effort"` // 推理努力度 => high | medium | low | minimal
 	Stream          bool   `mapstructure:"stream"`           // 是否使用流式输出
+	ToolCallMode    string `mapstructure:"tool_call_mode"`   // 工具调用方式 => native | xml
+
+	MaxRetries     uint          `mapstructure:"max_retries"`      // 请求失败 (408、429、5xx、连接重置) 时的最大重试次数
+	RetryBaseDelay time.Duration `mapstructure:"retry_base_delay"` // 第一次重试前的等待时间，之后指数退避
+
+	Prices []*ModelPrice `mapstructure:"prices"` // 各模型的价格，用于计算花费
+
+	DebugHTTP       bool     `mapstructure:"debug_http"`       // 是否在日志中记录请求体与对话内容，默认只记录 URL 与屏蔽密钥后的请求头
+	SensitiveFields []string `mapstructure:"sensitive_fields"` // 记录日志时额外屏蔽的 JSON 字段与 URL 参数
+
+	Fallbacks []*FallbackConfig `mapstructure:"fallbacks"` // 主 Provider 限流、超时或 5xx 时依次尝试的 Provider/Model
+
+	Profiles       map[string]*ProfileConfig `mapstructure:"profiles"`        // 命名的 Provider/Model 组合
+	DefaultProfile string                    `mapstructure:"default_profile"` // 未指定 --profile 时使用的 profile
+}
+
+// FallbackConfig is a provider/model of the fallback chain. The empty fields are inherited from the
+// primary provider, except base_url, custom_api_path and api_key if the provider is different.
+type FallbackConfig struct {
+	Provider      string `mapstructure:"provider"`
+	BaseURL       string `mapstructure:"base_url"`
+	CustomAPIPath string `mapstructure:"custom_api_path"`
+	Model         string `mapstructure:"model"`
+	APIKey        string `mapstructure:"api_key"`
+	APIKeyEnv     string `mapstructure:"api_key_env"`
+	APIKeyCmd     string `mapstructure:"api_key_cmd"`
+}
+
+// FallbackConfigs returns the configs of the fallbacks, in order
+func (c *Config) FallbackConfigs() []*Config {
+	configs := make([]*Config, 0, len(c.Fallbacks))
+	for idx, fallback := range c.Fallbacks {
+		if fallback == nil {
+			continue
+		}
+
+		config := c.withEndpoint(fallback.Provider, fallback.BaseURL, fallback.CustomAPIPath, fallback.Model,
+			fallback.APIKey, fallback.APIKeyEnv, fallback.APIKeyCmd)
+		if fallback.APIKey != "" || fallback.APIKeyEnv != "" || fallback.APIKeyCmd != "" {
+			config.apiKeyKey = fmt.Sprintf("fallbacks[%d].api_key", idx)
+		}
+		config.Fallbacks = nil
+
+		configs = append(configs, config)
+	}
+
+	return configs
+}
+
+// ProfileConfig is a named provider/model of the profiles, selected by default_profile or --profile.
+// The empty fields are inherited from the top level config, except base_url, custom_api_path
+// and api_key if the provider is different.
+type ProfileConfig struct {
+	Provider        string `mapstructure:"provider"`
+	BaseURL         string `mapstructure:"base_url"`
+	CustomAPIPath   string `mapstructure:"custom_api_path"`
+	Model           string `mapstructure:"model"`
+	APIKey          string `mapstructure:"api_key"`
+	APIKeyEnv       string `mapstructure:"api_key_env"`
+	APIKeyCmd       string `mapstructure:"api_key_cmd"`
+	ReasoningEffort string `mapstructure:"reasoning_effort"`
+}
+
+// WithProfile returns a copy of the config with the profile applied, the default profile if name is empty.
+// The profile is always applied to the top level config, so the profiles can be switched back and forth.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	root := c
+	if c.root != nil {
+		root = c.root
+	}
+
+	// NOTE: The keys of the profiles are lowercased by viper
+	name = strings.ToLower(name)
+	if name == "" {
+		name = strings.ToLower(root.DefaultProfile)
+	}
+	if name == "" {
+		config := *root
+		config.root = root
+		return &config, nil
+	}
+
+	profile := root.Profiles[name]
+	if profile == nil {
+		return nil, fmt.Errorf("%w '%s', the profiles are: %s",
+			ErrUnknownProfile, name, strings.Join(root.ProfileNames(), ", "))
+	}
+
+	config := root.withEndpoint(profile.Provider, profile.BaseURL, profile.CustomAPIPath, profile.Model,
+		profile.APIKey, profile.APIKeyEnv, profile.APIKeyCmd)
+	if profile.APIKey != "" || profile.APIKeyEnv != "" || profile.APIKeyCmd != "" {
+		config.apiKeyKey = "profiles." + name + ".api_key"
+	}
+	if profile.ReasoningEffort != "" {
+		config.ReasoningEffort = profile.ReasoningEffort
+	}
+	config.root = root
+	config.profile = name
+
+	return config, nil
+}
+
+// Path returns the path of client.yaml the config is loaded from
+func (c *Config) Path() string { return c.cfgPath }
+
+// Profile returns the name of the profile applied to the config, empty if none
+func (c *Config) Profile() string { return c.profile }
+
+// ProfileNames returns the sorted names of the profiles
+func (c *Config) ProfileNames() []string {
+	root := c
+	if c.root != nil {
+		root = c.root
+	}
+
+	names := make([]string, 0, len(root.Profiles))
+	for name := range root.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// withEndpoint returns a copy of the config with the non-empty fields replaced.
+// Any of apiKey, apiKeyEnv and apiKeyCmd replaces the API key source of the config.
+func (c *Config) withEndpoint(provider, baseURL, customAPIPath, model, apiKey, apiKeyEnv, apiKeyCmd string) *Config {
+	config := *c
+	if provider != "" && provider != c.Provider {
+		// NOTE: The endpoint and the API key of another provider are never reused
+		config.Provider = provider
+		config.BaseURL, config.CustomAPIPath = "", ""
+		config.APIKey, config.APIKeyEnv, config.APIKeyCmd = "", "", ""
+	}
+
+	if baseURL != "" {
+		config.BaseURL = baseURL
+	}
+	if customAPIPath != "" {
+		config.CustomAPIPath = customAPIPath
+	}
+	if model != "" {
+		config.Model = model
+	}
+	if apiKey != "" || apiKeyEnv != "" || apiKeyCmd != "" {
+		config.APIKey, config.APIKeyEnv, config.APIKeyCmd = apiKey, apiKeyEnv, apiKeyCmd
+	}
+
+	return &config
 }
 
 // NewDefaultConfig returns a new Config with default values
@@ -95,6 +288,10 @@ func NewDefaultConfig(logger log.Logger) (*Config, error) {
 		MaxTurns:        DefaultMaxTurns,
 		MaxTokens:       DefaultMaxTokens,
 		ReasoningEffort: DefaultReasoningEffort,
+		ToolCallMode:    DefaultToolCallMode,
+
+		MaxRetries:     DefaultMaxRetries,
+		RetryBaseDelay: DefaultRetryBaseDelay,
 	}, nil
 }
 
@@ -125,8 +322,16 @@ func NewConfigFromFile(configPath string, logger log.Logger) (*Config, error) {
 		)
 	}
 
+	// NOTE: The K_CLI_* environment variables override the scalar fields. Unmarshal, unlike UnmarshalKey,
+	// merges the environment variables bound to the keys nested in K-CLI.
+	if err := bindEnv(opts.viper); err != nil {
+		return nil, err
+	}
+
 	// Unmarshal the configuration into Options struct
-	if err := opts.viper.UnmarshalKey(YamlKeyBot, opts); err != nil {
+	if err := opts.viper.Unmarshal(&struct {
+		Config *Config `mapstructure:"k-cli"`
+	}{opts}); err != nil {
 		return nil, fmt.Errorf(
 			"failed to parse configuration from %s: %w. "+
 				"Please check your configuration syntax and ensure all field names match the expected configuration options",
@@ -135,25 +340,192 @@ func NewConfigFromFile(configPath string, logger log.Logger) (*Config, error) {
 		)
 	}
 
+	// NOTE: api_key_env and api_key_cmd are resolved when the provider is created, see ResolveAPIKey
+	opts.apiKeys = &sync.Map{}
+
 	// Validate the loaded configuration
 	if err := opts.Validate(); err != nil {
 		return nil, fmt.Errorf(
-			"invalid configuration values in %s: %w. "+
-				"Please review your configuration values and ensure they meet the required constraints",
+			"invalid configuration values in %s, "+
+				"please review your configuration values and ensure they meet the required constraints:\n%w",
 			configPath,
 			err,
 		)
 	}
 
-	return opts, nil
+	// NOTE: Apply the default profile, if any
+	config, err := opts.WithProfile("")
+	if err != nil {
+		return nil, fmt.Errorf("invalid default_profile in %s: %w", configPath, err)
+	}
+
+	return config, nil
+}
+
+// ConfigError is a problem of a value of client.yaml, Key is the YAML key of the value
+type ConfigError struct {
+	Key string
+	Err error
 }
 
-// Validate validates the loaded configuration
-// TODO: To implement
+func (e *ConfigError) Error() string { return e.Key + ": " + e.Err.Error() }
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// Validate validates the loaded configuration and fills the defaults of the empty values.
+// All the problems are reported at once, joined as ConfigError with their YAML key.
 func (svr *Config) Validate() error {
 	if svr.StorageType == "" {
 		svr.StorageType = DefaultStorageType
 	}
+	if svr.ToolCallMode == "" {
+		svr.ToolCallMode = DefaultToolCallMode
+	}
+
+	var errs []error
+	report := func(key string, format string, args ...any) {
+		errs = append(errs, &ConfigError{Key: YamlKeyBot + "." + key, Err: fmt.Errorf(format, args...)})
+	}
+
+	// NOTE: The profiles and the fallbacks are checked like the top level config,
+	// the values inherited from the top level are reported once
+	svr.validateEndpoint("", nil, report)
+	for _, name := range svr.ProfileNames() {
+		if profile, err := svr.WithProfile(name); err == nil {
+			profile.validateEndpoint("profiles."+name+".", svr, report)
+		}
+	}
+	for idx, fallback := range svr.FallbackConfigs() {
+		fallback.validateEndpoint(fmt.Sprintf("fallbacks[%d].", idx), svr, report)
+	}
+	if svr.DefaultProfile != "" && svr.Profiles[strings.ToLower(svr.DefaultProfile)] == nil {
+		report("default_profile", "%w '%s', the profiles are: %s",
+			ErrUnknownProfile, svr.DefaultProfile, strings.Join(svr.ProfileNames(), ", "))
+	}
+
+	if svr.MaxTurns == 0 {
+		report("max_turns", "must be greater than 0")
+	}
+	if svr.MaxTokens == 0 || svr.MaxTokens > MaxTokensLimit {
+		report("max_tokens", "must be between 1 and %d, got %d", MaxTokensLimit, svr.MaxTokens)
+	}
+	if !slices.Contains(StorageTypes, svr.StorageType) {
+		report("storage_type", "must be one of %s, got '%s'", strings.Join(StorageTypes, ", "), svr.StorageType)
+	}
+	if svr.ToolCallMode != ToolCallModeNative && svr.ToolCallMode != ToolCallModeXML {
+		report("tool_call_mode", "must be %s or %s, got '%s'", ToolCallModeNative, ToolCallModeXML, svr.ToolCallMode)
+	}
+
+	if err := checkWritable(svr.MCPSvrPath); err != nil {
+		report("mcp_server_path", "%w", err)
+	}
+	if err := checkWritable(svr.PromptPath); err != nil {
+		report("prompt_path", "%w", err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateEndpoint checks the provider, base_url, api_key and reasoning_effort, prefix is the YAML key of the config.
+// The values inherited from root are skipped, root is nil for the top level config.
+func (svr *Config) validateEndpoint(
+	prefix string,
+	root *Config,
+	report func(key string, format string, args ...any),
+) {
+	if root != nil && svr.Provider == root.Provider && svr.BaseURL == root.BaseURL &&
+		svr.APIKey == root.APIKey && svr.APIKeyEnv == root.APIKeyEnv && svr.APIKeyCmd == root.APIKeyCmd &&
+		svr.ReasoningEffort == root.ReasoningEffort {
+		return
+	}
+
+	if !slices.Contains(Providers(), svr.Provider) && (root == nil || svr.Provider != root.Provider) {
+		report(prefix+"provider", "%w '%s', the registered providers are: %s",
+			ErrUnknownProvider, svr.Provider, strings.Join(Providers(), ", "))
+	}
+
+	baseURL, err := url.Parse(svr.BaseURL)
+	switch {
+	case root != nil && svr.BaseURL == root.BaseURL:
+	case svr.BaseURL == "":
+		report(prefix+"base_url", "must not be empty")
+	case err != nil:
+		report(prefix+"base_url", "%w", err)
+	case baseURL.Scheme != "http" && baseURL.Scheme != "https" || baseURL.Host == "":
+		report(prefix+"base_url", "must be an http(s) URL, got '%s'", svr.BaseURL)
+	}
+
+	// NOTE: The OpenAI compatible servers on the local machine usually do not need an API key
+	if svr.APIKey == "" && svr.APIKeyEnv == "" && svr.APIKeyCmd == "" &&
+		slices.Contains(ProvidersWithAPIKey, svr.Provider) &&
+		(root == nil || svr.Provider != root.Provider || svr.BaseURL != root.BaseURL) &&
+		!(svr.Provider == ProviderOpenAI && err == nil && isLoopbackHost(baseURL.Hostname())) {
+		report(prefix+"api_key", "is required by provider %s", svr.Provider)
+	}
+
+	if svr.APIKeyCmd != "" && strings.TrimSpace(svr.APIKeyCmd) == "" && (root == nil || svr.APIKeyCmd != root.APIKeyCmd) {
+		report(prefix+"api_key_cmd", "must not be blank")
+	}
+
+	if svr.ReasoningEffort != "" && !slices.Contains(ReasoningEfforts, svr.ReasoningEffort) &&
+		(root == nil || svr.ReasoningEffort != root.ReasoningEffort) {
+		report(prefix+"reasoning_effort", "must be one of %s, got '%s'",
+			strings.Join(ReasoningEfforts, ", "), svr.ReasoningEffort)
+	}
+}
+
+// isLoopbackHost reports whether the host is localhost or a loopback address
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// checkWritable checks that the file can be written, or created in the nearest existing directory
+func checkWritable(path string) error {
+	path, err := ExpandUser(path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", path)
+		}
+
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0) //nolint:gosec
+		if err != nil {
+			return fmt.Errorf("%s is not writable: %w", path, err)
+		}
+		return file.Close()
+	}
+
+	// NOTE: The missing directories are created by the repositories
+	dir := filepath.Dir(path)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if parent := filepath.Dir(dir); parent != dir {
+			dir = parent
+			continue
+		}
+		return fmt.Errorf("no existing directory for %s", path)
+	}
+
+	file, err := os.CreateTemp(dir, ".k-cli-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	_ = file.Close()
 
-	return nil
+	return os.Remove(file.Name())
 }
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// EnvPrefix is the prefix of the environment variables overriding client.yaml, e.g. K_CLI_API_KEY
+	EnvPrefix = "K_CLI"
+
+	// APIKeyCmdTimeout is the maximum time to run api_key_cmd
+	APIKeyCmdTimeout = 10 * time.Second
+)
+
+// EnvName returns the environment variable overriding the key of client.yaml, e.g. K_CLI_MAX_TURNS for max_turns
+func EnvName(key string) string { return EnvPrefix + "_" + strings.ToUpper(key) }
+
+// bindEnv binds the K_CLI_* environment variables to the scalar fields of Config
+func bindEnv(v *viper.Viper) error {
+	typ := reflect.TypeFor[Config]()
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if key == "" {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		default:
+			continue // NOTE: The lists and the maps are only configured in client.yaml
+		}
+
+		if err := v.BindEnv(YamlKeyBot+"."+key, EnvName(key)); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", EnvName(key), err)
+		}
+	}
+
+	return nil
+}
+
+// ResolveAPIKey returns a copy of the config with api_key_env or api_key_cmd resolved into APIKey.
+// It is called when the provider is created, so only the keys of the providers in use are resolved.
+// The API keys are only kept in memory, never written back to client.yaml.
+func (c *Config) ResolveAPIKey() (*Config, error) {
+	if c.APIKey != "" || (c.APIKeyEnv == "" && c.APIKeyCmd == "") {
+		return c, nil
+	}
+
+	key := c.apiKeyKey
+	if key == "" {
+		key = "api_key"
+	}
+
+	// NOTE: The output of api_key_cmd is cached, e.g. switching back to a profile does not run it again
+	cacheKey := c.APIKeyEnv + "\x00" + c.APIKeyCmd
+	if c.apiKeys != nil {
+		if apiKey, ok := c.apiKeys.Load(cacheKey); ok {
+			config := *c
+			config.APIKey = apiKey.(string)
+			return &config, nil
+		}
+	}
+
+	apiKey, err := resolveAPIKey(c.APIKeyEnv, c.APIKeyCmd)
+	if err != nil {
+		return nil, &ConfigError{Key: YamlKeyBot + "." + key, Err: err}
+	}
+	if c.apiKeys != nil && c.APIKeyCmd != "" {
+		c.apiKeys.Store(cacheKey, apiKey)
+	}
+
+	config := *c
+	config.APIKey = apiKey
+
+	return &config, nil
+}
+
+// resolveAPIKey returns the value of the environment variable env, else the output of cmd.
+// The errors never contain the API key.
+func resolveAPIKey(env, cmd string) (string, error) {
+	if env != "" {
+		if value := strings.TrimSpace(os.Getenv(env)); value != "" {
+			return value, nil
+		}
+		if cmd == "" {
+			return "", fmt.Errorf("environment variable %s of api_key_env is not set", env)
+		}
+	}
+
+	if cmd == "" {
+		return "", nil
+	}
+
+	// NOTE: The command is not run by a shell, e.g. `pass show openrouter`
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return "", errors.New("api_key_cmd must not be blank")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), APIKeyCmdTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, fields[0], fields[1:]...) //nolint:gosec
+	command.Stdout, command.Stderr = &stdout, &stderr
+	if err := command.Run(); err != nil {
+		return "", fmt.Errorf("failed to run api_key_cmd %s: %w: %s", fields[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	// NOTE: Only the first line is the key, e.g. `pass show` prints the metadata after the password
+	value, _, _ := strings.Cut(stdout.String(), "\n")
+	if value = strings.TrimSpace(value); value == "" {
+		return "", fmt.Errorf("api_key_cmd %s printed no API key", fields[0])
+	}
+
+	return value, nil
+}
@@ -0,0 +1,232 @@
+package client
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newValidConfig returns a valid config whose MCP server and prompt files are in a temporary directory
+func newValidConfig(t *testing.T) *Config {
+	dir := t.TempDir()
+
+	return &Config{
+		Provider:      ProviderOpenAI,
+		BaseURL:       "https://openrouter.ai/api",
+		CustomAPIPath: DefaultCustomAPIPath,
+		Model:         DefaultModel,
+		APIKey:        "sk-test",
+
+		MCPSvrPath: filepath.Join(dir, "mcp_servers.jsonl"),
+		PromptPath: filepath.Join(dir, "config", "prompts.jsonl"),
+
+		MaxTurns:        DefaultMaxTurns,
+		MaxTokens:       DefaultMaxTokens,
+		ReasoningEffort: DefaultReasoningEffort,
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	config := newValidConfig(t)
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if config.StorageType != DefaultStorageType || config.ToolCallMode != DefaultToolCallMode {
+		t.Errorf("defaults = %q, %q", config.StorageType, config.ToolCallMode)
+	}
+
+	// NOTE: OpenAI compatible servers on the local machine do not need an API key
+	config.BaseURL, config.APIKey = "http://127.0.0.1:11434", ""
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate() on localhost error = %v", err)
+	}
+}
+
+func TestConfig_ValidateReportsAllProblems(t *testing.T) {
+	config := newValidConfig(t)
+	config.Provider = "Unknown"
+	config.BaseURL = "api.anthropic.com"
+	config.APIKey = ""
+	config.ReasoningEffort = "extreme"
+	config.MaxTurns = 0
+	config.MaxTokens = MaxTokensLimit + 1
+	config.StorageType = "redis"
+	config.ToolCallMode = "json"
+	config.MCPSvrPath = t.TempDir()
+	config.Profiles = map[string]*ProfileConfig{"local": {Provider: "Local"}, "blank": {APIKeyCmd: "  "}}
+	config.Fallbacks = []*FallbackConfig{{Provider: ProviderAnthropic, BaseURL: "://"}}
+	config.DefaultProfile = "missing"
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Validate() error = nil")
+	}
+
+	for _, key := range []string{
+		"K-CLI.provider", "K-CLI.base_url", "K-CLI.reasoning_effort",
+		"K-CLI.max_turns", "K-CLI.max_tokens", "K-CLI.storage_type", "K-CLI.tool_call_mode",
+		"K-CLI.mcp_server_path", "K-CLI.profiles.local.provider", "K-CLI.fallbacks[0].base_url", "K-CLI.fallbacks[0].api_key",
+		"K-CLI.profiles.blank.api_key_cmd", "K-CLI.default_profile",
+	} {
+		if !strings.Contains(err.Error(), key+": ") {
+			t.Errorf("Validate() error does not report %s:\n%v", key, err)
+		}
+	}
+	if strings.Contains(err.Error(), "K-CLI.prompt_path") {
+		t.Errorf("Validate() error reports the valid prompt_path:\n%v", err)
+	}
+
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Errorf("Validate() error is not a ConfigError: %T", err)
+	}
+	if !errors.Is(err, ErrUnknownProvider) || !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Validate() error does not wrap ErrUnknownProvider and ErrUnknownProfile: %v", err)
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkWritable(file); err != nil {
+		t.Errorf("checkWritable(existing file) error = %v", err)
+	}
+	if err := checkWritable(filepath.Join(dir, "a", "b", "file")); err != nil {
+		t.Errorf("checkWritable(missing directories) error = %v", err)
+	}
+	if err := checkWritable(dir); err == nil {
+		t.Error("checkWritable(directory) error = nil")
+	}
+	if err := checkWritable(filepath.Join(file, "child")); err == nil {
+		t.Error("checkWritable(child of a file) error = nil")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("checkWritable left files behind: %v", entries)
+	}
+}
+
+func TestNewConfigFromFile_EnvAndSecrets(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv(EnvName("model"), "env/model")
+	t.Setenv(EnvName("max_turns"), "3")
+	t.Setenv(EnvName("retry_base_delay"), "2s")
+	t.Setenv("TEST_OPENROUTER_API_KEY", "sk-from-env")
+
+	t.Setenv("TEST_UNSET_API_KEY", "")
+
+	// NOTE: The command counts its runs, the API key is only resolved once
+	runs := filepath.Join(dir, "runs")
+	keyCmd := filepath.Join(dir, "key.sh")
+	if err := os.WriteFile(keyCmd, []byte("#!/bin/sh\necho run >> "+runs+"\necho sk-from-cmd\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	cfgPath := filepath.Join(dir, "client.yaml")
+	content := `K-CLI:
+  provider: "OpenAI"
+  base_url: "https://openrouter.ai/api"
+  model: "file/model"
+  api_key_env: "TEST_OPENROUTER_API_KEY"
+  mcp_server_path: "` + filepath.Join(dir, "mcp_servers.jsonl") + `"
+  prompt_path: "` + filepath.Join(dir, "prompts.jsonl") + `"
+  max_turns: 10
+  max_tokens: 1024
+  profiles:
+    claude:
+      provider: "Anthropic"
+      base_url: "https://api.anthropic.com"
+      api_key_cmd: "` + keyCmd + `"
+    unused:
+      provider: "Gemini"
+      base_url: "https://generativelanguage.googleapis.com"
+      api_key_env: "TEST_UNSET_API_KEY"
+  fallbacks:
+    - provider: "Gemini"
+      base_url: "https://generativelanguage.googleapis.com"
+      api_key_cmd: "false"
+`
+	if err := os.WriteFile(cfgPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := NewConfigFromFile(cfgPath, &discardLogger{})
+	if err != nil {
+		t.Fatalf("NewConfigFromFile() error = %v", err)
+	}
+	if config.Model != "env/model" || config.MaxTurns != 3 || config.RetryBaseDelay != 2*time.Second {
+		t.Errorf("config = %s, %d, %s, want the environment overrides",
+			config.Model, config.MaxTurns, config.RetryBaseDelay)
+	}
+	if config.APIKey != "" {
+		t.Errorf("APIKey = %q, want it resolved when the provider is created", config.APIKey)
+	}
+	if resolved, err := config.ResolveAPIKey(); err != nil || resolved.APIKey != "sk-from-env" {
+		t.Errorf("ResolveAPIKey() = %v, %v, want the value of api_key_env", resolved, err)
+	}
+
+	if _, err := os.Stat(runs); !os.IsNotExist(err) {
+		t.Errorf("api_key_cmd should not run before the profile is used, got %v", err)
+	}
+	for range 2 {
+		profile, err := config.WithProfile("claude")
+		if err != nil {
+			t.Fatalf("WithProfile() error = %v", err)
+		}
+		if resolved, err := profile.ResolveAPIKey(); err != nil || resolved.APIKey != "sk-from-cmd" {
+			t.Errorf("profile ResolveAPIKey() = %v, %v, want the output of api_key_cmd", resolved, err)
+		}
+	}
+	if data, _ := os.ReadFile(runs); string(data) != "run\n" {
+		t.Errorf("api_key_cmd ran %q, want once", data)
+	}
+
+	// NOTE: The keys of the unused profile and the fallback fail only when their provider is created
+	unused, err := config.WithProfile("unused")
+	if err != nil {
+		t.Fatalf("WithProfile() error = %v", err)
+	}
+	var configErr *ConfigError
+	if _, err := NewProvider(unused, &discardLogger{}); !errors.As(err, &configErr) ||
+		configErr.Key != "K-CLI.profiles.unused.api_key" {
+		t.Errorf("NewProvider(unused) error = %v, want the api_key of the profile", err)
+	}
+	if _, err := NewProvider(config.FallbackConfigs()[0], &discardLogger{}); !errors.As(err, &configErr) ||
+		configErr.Key != "K-CLI.fallbacks[0].api_key" {
+		t.Errorf("NewProvider(fallback) error = %v, want the api_key of the fallback", err)
+	}
+
+	if data, _ := os.ReadFile(cfgPath); string(data) != content {
+		t.Errorf("client.yaml was rewritten:\n%s", data)
+	}
+}
+
+func TestResolveAPIKey(t *testing.T) {
+	t.Setenv("TEST_UNSET_API_KEY", "")
+
+	if key, err := resolveAPIKey("", ""); err != nil || key != "" {
+		t.Errorf("resolveAPIKey(nothing) = %q, %v", key, err)
+	}
+	if _, err := resolveAPIKey("TEST_UNSET_API_KEY", ""); err == nil {
+		t.Error("resolveAPIKey(unset api_key_env) error = nil")
+	}
+	if key, err := resolveAPIKey("TEST_UNSET_API_KEY", "printf sk-first\\nmetadata"); err != nil || key != "sk-first" {
+		t.Errorf("resolveAPIKey(api_key_cmd) = %q, %v, want the first line", key, err)
+	}
+	if _, err := resolveAPIKey("", "false"); err == nil {
+		t.Error("resolveAPIKey(failing api_key_cmd) error = nil")
+	}
+	if _, err := resolveAPIKey("", " \t "); err == nil {
+		t.Error("resolveAPIKey(blank api_key_cmd) error = nil")
+	}
+}
@@ -0,0 +1,58 @@
+package client
+
+import "context"
+
+// EventType is the type of an Event streamed by Manager.StreamUserTextInput
+type EventType string
+
+const (
+	EventTextDelta       EventType = "text_delta"        // A fragment of the answer
+	EventReasoningDelta  EventType = "reasoning_delta"   // A fragment of the reasoning (thinking)
+	EventToolCallStarted EventType = "tool_call_started" // An MCP tool is being called
+	EventToolResult      EventType = "tool_result"       // An MCP tool returned its result
+	EventTurnFinished    EventType = "turn_finished"     // The final answer of the turn, the last event on success
+	EventError           EventType = "error"             // The turn failed, the last event on failure
+	EventReloaded        EventType = "reloaded"          // A file changed since the last turn was reloaded
+)
+
+// Event is an event of a turn, the fields set depend on the Type
+type Event struct {
+	Type EventType
+
+	Delta    string         // EventTextDelta, EventReasoningDelta
+	ToolCall *ToolCallEvent // EventToolCallStarted, EventToolResult
+	Message  *Message       // EventTurnFinished
+	Err      error          // EventError
+	Reload   *ReloadEvent   // EventReloaded
+}
+
+// ToolCallEvent describes the MCP tool call of an EventToolCallStarted or EventToolResult
+type ToolCallEvent struct {
+	ID        string // ID of the native tool call, empty for the XML tool use
+	Server    string
+	Tool      string
+	Arguments map[string]any
+	Result    string // EventToolResult only
+}
+
+// ReloadEvent describes a file reloaded by Manager.Watch, sent as EventReloaded at the start of the next turn
+type ReloadEvent struct {
+	Path    string   // client.yaml, mcp_servers.jsonl 或 prompts.jsonl
+	Servers []string // 重新连接或断开的 MCP Server
+	Err     error    // 重新加载失败时的错误，之前的配置保持不变
+}
+
+type eventHandlerKey struct{}
+
+// WithEventHandler returns a context whose events are passed to handler,
+// the providers and the manager emit the events of a turn with EmitEvent
+func WithEventHandler(ctx context.Context, handler func(*Event)) context.Context {
+	return context.WithValue(ctx, eventHandlerKey{}, handler)
+}
+
+// EmitEvent passes the event to the handler of ctx, if any
+func EmitEvent(ctx context.Context, event *Event) {
+	if handler, ok := ctx.Value(eventHandlerKey{}).(func(*Event)); ok && handler != nil {
+		handler(event)
+	}
+}
@@ -0,0 +1,265 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/kydenul/log"
+)
+
+const (
+	// MaxJSONLLineSize is the maximum size of a line of the JSONL files, a chat with images can be large
+	MaxJSONLLineSize = 64 << 20
+
+	// BackupSuffix is appended to a JSONL file for the previous version of the file
+	BackupSuffix = ".bak"
+	// CorruptSuffix is appended to a JSONL file that failed to parse and was recovered from the backup
+	CorruptSuffix = ".corrupt"
+
+	// DefaultJSONLFilePerm is the permission of a JSONL file written for the first time
+	DefaultJSONLFilePerm = 0o600
+)
+
+// readJSONL reads the JSONL file, returning the items and the number of lines that failed to parse
+func readJSONL[T any](jsonl string) ([]*T, int, error) {
+	file, err := os.Open(jsonl) //nolint:gosec
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxJSONLLineSize)
+
+	items, invalid := make([]*T, 0, 128), 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		item := new(T)
+		if err := sonic.UnmarshalString(line, item); err != nil {
+			invalid++
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	return items, invalid, scanner.Err()
+}
+
+// loadJSONL loads the items of the JSONL file, skipping the invalid lines with a warning.
+//
+// NOTE: If the file is unreadable or none of its lines parse, e.g. truncated to garbage by a crash of an older
+// k-cli, and its backup parses, a copy of the file is kept as <file>.corrupt and the backup is restored onto
+// the file, so that the next write backs up the good version and not the corrupt one.
+func loadJSONL[T any](jsonl string, logger log.Logger) ([]*T, error) {
+	items, invalid, err := readJSONL[T](jsonl)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil && (invalid == 0 || len(items) > 0) {
+		if invalid > 0 {
+			logger.Warnf("skipped %d invalid lines of %s", invalid, jsonl)
+		}
+		return items, nil
+	}
+
+	backup, backupInvalid, backupErr := readJSONL[T](jsonl + BackupSuffix)
+	if backupErr != nil || backupInvalid > 0 {
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Warnf("skipped %d invalid lines of %s", invalid, jsonl)
+		return items, nil
+	}
+
+	corrupt := corruptPath(jsonl)
+	if err := copyFile(jsonl, corrupt); err != nil {
+		return nil, fmt.Errorf("failed to keep %s before recovering it: %w", jsonl, err)
+	}
+	if err := restoreFile(jsonl+BackupSuffix, jsonl); err != nil {
+		return nil, fmt.Errorf("failed to restore %s from %s: %w", jsonl, jsonl+BackupSuffix, err)
+	}
+	logger.Warnf("%s failed to parse (%d invalid lines, error: %v), restored %d items from %s, the file is kept as %s",
+		jsonl, invalid, err, len(backup), jsonl+BackupSuffix, corrupt)
+
+	return backup, nil
+}
+
+// corruptPath returns <file>.corrupt, or <file>.corrupt.<n> if the file was already recovered before
+func corruptPath(jsonl string) string {
+	path := jsonl + CorruptSuffix
+	for n := 1; ; n++ {
+		if _, err := os.Lstat(path); errors.Is(err, fs.ErrNotExist) {
+			return path
+		}
+		path = fmt.Sprintf("%s%s.%d", jsonl, CorruptSuffix, n)
+	}
+}
+
+// writeJSONL writes the items to the JSONL file atomically, the previous version is kept as <file>.bak
+func writeJSONL[T any](jsonl string, items []*T) error {
+	perm := fs.FileMode(DefaultJSONLFilePerm)
+	if info, err := os.Stat(jsonl); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	write := func(w io.Writer) error {
+		for _, item := range items {
+			data, err := sonic.Marshal(item)
+			if err != nil {
+				return err
+			}
+
+			if _, err := w.Write(append(data, '\n')); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	backup := func() error {
+		if err := backupFile(jsonl); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", jsonl, err)
+		}
+		return nil
+	}
+
+	return writeFileAtomic(jsonl, perm, write, backup)
+}
+
+// restoreFile replaces the file with its backup atomically
+func restoreFile(backup, file string) error {
+	in, err := os.Open(backup) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(file, info.Mode().Perm(), func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	}, nil)
+}
+
+// writeFileAtomic writes the file atomically: write writes the content to a temporary file in the same
+// directory, which is synced, then renamed over the file. beforeRename, if not nil, runs right before the rename.
+func writeFileAtomic(
+	file string, perm fs.FileMode, write func(io.Writer) error, beforeRename func() error,
+) (err error) {
+	dir := filepath.Dir(file)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(file)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	if err := write(w); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return fmt.Errorf("failed to chmod %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmp.Name(), err)
+	}
+
+	if beforeRename != nil {
+		if err := beforeRename(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", file, err)
+	}
+
+	// NOTE: Sync the directory so that the rename survives a crash, not supported on every platform
+	if d, err := os.Open(dir); err == nil { //nolint:gosec
+		_ = d.Sync()
+		_ = d.Close()
+	}
+
+	return nil
+}
+
+// backupFile replaces <file>.bak with the file, a hard link if possible. An empty or missing file is not
+// backed up, so that the backup is never replaced by nothing.
+func backupFile(file string) error {
+	info, err := os.Stat(file)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.Size() == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	backup := file + BackupSuffix
+	if err := os.Remove(backup); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Link(file, backup); err == nil {
+		return nil
+	}
+
+	// NOTE: Hard links are not supported by every file system
+	return copyFile(file, backup)
+}
+
+// copyFile copies src to dst, replacing dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm()) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSONL(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "prompts.jsonl")
+
+	if err := writeJSONL(file, []*PromptItem{{Name: "v1"}}); err != nil {
+		t.Fatalf("writeJSONL() error = %v", err)
+	}
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != DefaultJSONLFilePerm {
+		t.Errorf("writeJSONL() created %v, %v, want mode %o", info, err, DefaultJSONLFilePerm)
+	}
+	if _, err := os.Stat(file + BackupSuffix); !os.IsNotExist(err) {
+		t.Errorf("writeJSONL() of a new file should not create a backup, got %v", err)
+	}
+
+	// The permission of the file is kept, the previous version becomes the backup
+	if err := os.Chmod(file, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJSONL(file, []*PromptItem{{Name: "v2"}}); err != nil {
+		t.Fatalf("writeJSONL() error = %v", err)
+	}
+	if err := writeJSONL(file, []*PromptItem{{Name: "v3"}}); err != nil {
+		t.Fatalf("writeJSONL() error = %v", err)
+	}
+
+	if data, _ := os.ReadFile(file); !strings.Contains(string(data), `"v3"`) {
+		t.Errorf("file = %s, want v3", data)
+	}
+	if data, _ := os.ReadFile(file + BackupSuffix); !strings.Contains(string(data), `"v2"`) {
+		t.Errorf("backup = %s, want v2", data)
+	}
+	if info, _ := os.Stat(file); info.Mode().Perm() != 0o640 {
+		t.Errorf("writeJSONL() changed the mode to %o", info.Mode().Perm())
+	}
+
+	// No temporary file is left behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("directory has %d entries, want the file and its backup", len(entries))
+	}
+
+	// A failed write leaves the file untouched
+	if err := writeJSONL(file, []*Chat{{ID: "bad", Messages: []*Message{{Content: func() {}}}}}); err == nil {
+		t.Error("writeJSONL() of an unmarshalable item should fail")
+	}
+	if data, _ := os.ReadFile(file); !strings.Contains(string(data), `"v3"`) {
+		t.Errorf("failed writeJSONL() modified the file: %s", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("failed writeJSONL() left %d entries", len(entries))
+	}
+}
+
+func TestLoadJSONL(t *testing.T) {
+	t.Run("long lines", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "chats.jsonl")
+		chat := createTestChat("large")
+		chat.Messages[0].Content = strings.Repeat("x", 1<<20)
+		if err := persistChatToFile(file, []*Chat{chat}); err != nil {
+			t.Fatal(err)
+		}
+
+		chats, err := loadChatFromFile(file, &discardLogger{})
+		if err != nil || len(chats) != 1 || chats[0].Messages[0].Content != chat.Messages[0].Content {
+			t.Errorf("loadChatFromFile() = %d chats, %v, want the large chat", len(chats), err)
+		}
+	})
+
+	t.Run("keeps the valid lines of a partly invalid file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "mcp_servers.jsonl")
+		if err := writeJSONL(file, []*MCPSvrItem{{Name: "a"}, {Name: "b"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeJSONL(file, []*MCPSvrItem{{Name: "a"}, {Name: "b"}, {Name: "c"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Truncated in the middle of the last line, the latest write must not be replaced by the backup
+		data, _ := os.ReadFile(file)
+		if err := os.WriteFile(file, append(data, []byte("{\"name\":\"d")...), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		configs, err := loadMCPServerConfigsFromJSONL(file, &discardLogger{})
+		if err != nil || len(configs) != 3 {
+			t.Fatalf("loadMCPServerConfigsFromJSONL() = %d configs, %v, want the 3 valid lines", len(configs), err)
+		}
+		if _, err := os.Stat(file + CorruptSuffix); !os.IsNotExist(err) {
+			t.Errorf("partly invalid file should not be kept as corrupt, got %v", err)
+		}
+	})
+
+	t.Run("recovers from the backup", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "mcp_servers.jsonl")
+		if err := writeJSONL(file, []*MCPSvrItem{{Name: "a"}, {Name: "b"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeJSONL(file, []*MCPSvrItem{{Name: "a"}, {Name: "b"}, {Name: "c"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		// None of the lines parse
+		corrupt := []byte("\x00\x00\x00\x00\n")
+		if err := os.WriteFile(file, corrupt, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		configs, err := loadMCPServerConfigsFromJSONL(file, &discardLogger{})
+		if err != nil || len(configs) != 2 {
+			t.Fatalf("loadMCPServerConfigsFromJSONL() = %d configs, %v, want the 2 of the backup", len(configs), err)
+		}
+		if kept, _ := os.ReadFile(file + CorruptSuffix); string(kept) != string(corrupt) {
+			t.Errorf("corrupt file kept as %q, want %q", kept, corrupt)
+		}
+
+		// The backup is restored onto the file, the next write backs up the good version
+		if configs, err := loadMCPServerConfigsFromJSONL(file, &discardLogger{}); err != nil || len(configs) != 2 {
+			t.Fatalf("second loadMCPServerConfigsFromJSONL() = %d configs, %v, want the 2 restored", len(configs), err)
+		}
+		if err := writeJSONL(file, []*MCPSvrItem{{Name: "d"}}); err != nil {
+			t.Fatal(err)
+		}
+		backup, invalid, err := readJSONL[MCPSvrItem](file + BackupSuffix)
+		if err != nil || invalid != 0 || len(backup) != 2 || backup[0].Name != "a" || backup[1].Name != "b" {
+			t.Errorf("backup after recovery = %d items, %d invalid, %v, want a and b", len(backup), invalid, err)
+		}
+
+		// A second recovery keeps the first corrupt file
+		if err := os.WriteFile(file, []byte("garbage\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if configs, err := loadMCPServerConfigsFromJSONL(file, &discardLogger{}); err != nil || len(configs) != 2 {
+			t.Fatalf("third loadMCPServerConfigsFromJSONL() = %d configs, %v, want the 2 of the backup", len(configs), err)
+		}
+		if kept, _ := os.ReadFile(file + CorruptSuffix); string(kept) != string(corrupt) {
+			t.Errorf("first corrupt file overwritten with %q", kept)
+		}
+		if kept, _ := os.ReadFile(file + CorruptSuffix + ".1"); string(kept) != "garbage\n" {
+			t.Errorf("second corrupt file kept as %q, want garbage", kept)
+		}
+	})
+
+	t.Run("skips invalid lines without a valid backup", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "prompts.jsonl")
+		if err := os.WriteFile(file, []byte("{\"name\":\"a\"}\nnot json\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		prompts, err := loadPromptFromJSONL(file, &discardLogger{})
+		if err != nil || len(prompts) != 1 || prompts[0].Name != "a" {
+			t.Errorf("loadPromptFromJSONL() = %v, %v, want prompt a", prompts, err)
+		}
+		if _, err := os.Stat(file + CorruptSuffix); !os.IsNotExist(err) {
+			t.Errorf("file without backup should not be kept as corrupt, got %v", err)
+		}
+	})
+}
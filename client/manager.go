@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
+	"github.com/bytedance/sonic"
 	"github.com/kydenul/log"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/samber/lo"
 	"github.com/spf13/cast"
 )
 
@@ -17,13 +20,24 @@ const (
 	RoleSystem    = "system"
 	RoleTool      = "tool"
 
-	ProviderOpenAI = "OpenAI"
-	ProviderOllama = "Ollama"
-	ProviderTaiji  = "Taiji"
+	ProviderOpenAI    = "OpenAI"
+	ProviderOllama    = "Ollama"
+	ProviderTaiji     = "Taiji"
+	ProviderAnthropic = "Anthropic"
+	ProviderGemini    = "Gemini"
 
 	DefaultChatMessageSize = DefaultMaxTurns
 )
 
+var (
+	// ErrNoResponse is returned when a turn finishes without an assistant message
+	ErrNoResponse = errors.New("no response from assistant")
+	// ErrMaxTurnsExceeded is returned when the MCP tool calls of a turn exceed Config.MaxTurns
+	ErrMaxTurnsExceeded = errors.New("max turns exceeded")
+	// ErrInvalidToolUse is returned when the tool use in the assistant response can not be parsed
+	ErrInvalidToolUse = errors.New("invalid tool use")
+)
+
 type Manager struct {
 	log.Logger
 
@@ -31,27 +45,29 @@ type Manager struct {
 	chat     *Chat      // current chat
 	messages []*Message // current message in chat
 
-	MCPMgr   *MCPSvrManager
-	provider Provider
+	MCPMgr    *MCPSvrManager
+	provider  Provider
+	fallbacks []*fallback // 主 Provider 失败时依次尝试
 
-	promptSvr    *PromptSvr
-	systemPrompt string
+	promptSvr     *PromptSvr
+	promptName    string          // prompt appended to the system prompt
+	systemPrompts map[bool]string // 本轮的 system prompt，按是否使用原生工具调用区分
 
 	chatID        string
 	continueExist bool
 
 	config *Config
-}
 
-type LLMStreamRet struct {
-	Err  error // First
-	Done bool  // Second
+	modelOverride string // SetModel 设置的模型，重新加载 client.yaml 时保留
 
-	ID    string
-	Model string
+	reloadMu sync.Mutex
+	reloads  map[string]func() *ReloadEvent // Watch 发现变化的文件 => 重新加载，在下一轮开始时执行
+}
 
-	Content  string
-	StreamCh <-chan StreamChunk
+// fallback is a provider of the fallback chain with its config
+type fallback struct {
+	provider Provider
+	config   *Config
 }
 
 type MCPToolUse struct {
@@ -67,21 +83,11 @@ func NewManager(
 	promptRepo PromptRepo,
 	chatID *string,
 	config *Config,
-) *Manager {
+) (*Manager, error) {
 	// NOTE Provider
-	var provider Provider
-	switch config.Provider {
-	case ProviderOpenAI:
-		provider = NewOpenAIFormatProvider(config, logger)
-
-	case ProviderOllama:
-		provider = NewOllmaFormatProvider(config, logger)
-
-	case ProviderTaiji:
-		provider = NewTaijiProvider(config, logger)
-
-	default: // OpenAI
-		provider = NewOpenAIFormatProvider(config, logger)
+	provider, fallbacks, err := newProviderChain(config, logger)
+	if err != nil {
+		return nil, err
 	}
 
 	// NOTE Manager
@@ -91,12 +97,14 @@ func NewManager(
 		chatSvr:  NewChatSvr(chatReop, logger),
 		messages: make([]*Message, 0, DefaultChatMessageSize),
 
-		systemPrompt: "",
-		promptSvr:    NewPromptSvr(promptRepo, logger),
+		systemPrompts: make(map[bool]string, 2),
+		promptSvr:     NewPromptSvr(promptRepo, logger),
+		promptName:    DefaultMCPPromptName,
 
-		MCPMgr:   NewMCPSvrManager(mcpReop, logger),
-		provider: provider,
-		config:   config,
+		MCPMgr:    NewMCPSvrManager(mcpReop, logger),
+		provider:  provider,
+		fallbacks: fallbacks,
+		config:    config,
 	}
 
 	// NOTE Generate new chat ID immediately
@@ -113,40 +121,46 @@ func NewManager(
 
 	mgr.MCPMgr.initMCPServer(context.Background())
 
-	return mgr
+	return mgr, nil
 }
 
-// HandleUserTextInput handle user TEXT input without any link, image
-func (mgr *Manager) HandleUserTextInput(userInput string) (*Message, error) {
-	mgr.Info("Starting chat session...")
+// newProviderChain creates the provider of the config and the providers of its fallbacks
+func newProviderChain(config *Config, logger log.Logger) (Provider, []*fallback, error) {
+	provider, err := NewProvider(config, logger)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// Load chat if chat_id was provided and not already loaded
-	if mgr.continueExist {
-		mgr.loadChat(context.Background())
-		mgr.Info("Chat loaded successfully")
+	fallbacks := make([]*fallback, 0, len(config.Fallbacks))
+	for idx, fallbackConfig := range config.FallbackConfigs() {
+		fallbackProvider, err := NewProvider(fallbackConfig, logger)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fallbacks[%d]: %w", idx, err)
+		}
+		fallbacks = append(fallbacks, &fallback{provider: fallbackProvider, config: fallbackConfig})
 	}
 
-	// NOTE 1. Init basic system prompt
-	promptBuilder := strings.Builder{}
-	promptBuilder.Reset()
-	promptBuilder.WriteString(TimePrompt + "\n")
+	return provider, fallbacks, nil
+}
 
-	// NOTE 2. Initialize MCP and system prompt if MCP server settings exist
-	if mgr.MCPMgr != nil {
-		promptBuilder.WriteString(
-			mgr.MCPMgr.Prompt(context.Background(), mgr.promptSvr) + "\n")
-	}
+// HandleUserTextInput handle user TEXT input without any link, image.
+// Canceling ctx aborts the provider stream and the MCP tool call in flight, the messages of the turn are dropped.
+func (mgr *Manager) HandleUserTextInput(ctx context.Context, userInput string) (*Message, error) {
+	mgr.Info("Starting chat session...")
 
-	// NOTE 3. Initialize prompt
-	if mgr.promptSvr != nil {
-		// TODO: 后续使用配置，支持多个 prompt, e.g. "MCP", "Knowledge"
-		prompt := mgr.promptSvr.PromptByName(DefaultMCPPromptName)
-		if prompt != nil {
-			promptBuilder.WriteString(prompt.Content + "\n")
+	// NOTE: Apply the files reloaded since the last turn
+	mgr.applyReloads(ctx)
+
+	// Load chat if chat_id was provided and not already loaded
+	if mgr.continueExist {
+		if err := mgr.loadChat(ctx); err != nil {
+			return nil, err
 		}
+		mgr.Info("Chat loaded successfully")
 	}
-	mgr.systemPrompt = promptBuilder.String()
-	mgr.Debugf("System prompt: %s", mgr.systemPrompt)
+
+	// NOTE 1-3. The system prompt is built for each tool call mode used in the turn, see systemPromptOf
+	clear(mgr.systemPrompts)
 
 	// NOTE 4. Show history messages
 	messageNum := len(mgr.messages)
@@ -156,13 +170,11 @@ func (mgr *Manager) HandleUserTextInput(userInput string) (*Message, error) {
 		for _, msg := range mgr.messages {
 			switch msg.Role {
 			case RoleUser:
-				mgr.Infof("\t👤 User: %s\n\n", msg.Content)
+				mgr.debugContent("\t👤 User: %s\n\n", msg.Content)
 			case RoleAssistant:
-				mgr.Infof("\t🤖 Assistant: %s\n\n", msg.Content)
+				mgr.debugContent("\t🤖 Assistant: %s\n\n", msg.Content)
 			}
 		}
-
-		mgr.Infoln("---")
 	}
 
 	// NOTE 5. Build user input
@@ -175,62 +187,92 @@ func (mgr *Manager) HandleUserTextInput(userInput string) (*Message, error) {
 
 	// NOTE 6. Process user input and get assistant response
 	var turn uint = 1
-	mgr.processUserMessage(&turn, message)
+	if err := mgr.processUserMessage(ctx, &turn, message); err != nil {
+		mgr.Errorf("failed to process user message: %v", err)
+
+		// Drop the messages of the failed turn, so that the user can retry
+		mgr.messages = mgr.messages[:messageNum]
+		return nil, err
+	}
 	mgr.Infof("==>> Use  %d turns MCP Server to complete the task.", turn-1)
 
-	// NOTE 7. Show lastest message
+	// NOTE 7. Return lastest message
 	if len(mgr.messages) > messageNum {
 		lastMsg := mgr.messages[len(mgr.messages)-1]
 		if lastMsg.Role == RoleAssistant {
-			fmt.Printf("🤖 Assistant: %s\n\n", lastMsg.Content)
+			return lastMsg, nil
 		}
-
-		return mgr.messages[len(mgr.messages)-1], nil
 	}
 
-	return nil, errors.New("no new message")
+	return nil, ErrNoResponse
+}
+
+// StreamUserTextInput handles the user input like HandleUserTextInput, streaming the events of the turn
+// as they happen. The last event is EventTurnFinished with the answer or EventError, then the channel is closed.
+// The channel must be drained, canceling ctx aborts the turn.
+func (mgr *Manager) StreamUserTextInput(ctx context.Context, userInput string) <-chan *Event {
+	events := make(chan *Event, DefaultStreamChunkSize)
+
+	go func() {
+		defer close(events)
+
+		msg, err := mgr.HandleUserTextInput(WithEventHandler(ctx, func(event *Event) {
+			events <- event
+		}), userInput)
+		if err != nil {
+			events <- &Event{Type: EventError, Err: err}
+			return
+		}
+
+		events <- &Event{Type: EventTurnFinished, Message: msg}
+	}()
+
+	return events
 }
 
-func (mgr *Manager) processUserMessage(turn *uint, message *Message) {
+func (mgr *Manager) processUserMessage(ctx context.Context, turn *uint, messages ...*Message) error {
 	if *turn > mgr.config.MaxTurns {
 		mgr.Errorf("MaxTurns %d exceeded", mgr.config.MaxTurns)
-		return
+		return fmt.Errorf("%w: %d", ErrMaxTurnsExceeded, mgr.config.MaxTurns)
 	}
 
-	mgr.Infof("Role: %s, Content: %s", message.Role, message.Content)
-	mgr.messages = append(mgr.messages, message)
+	for _, message := range messages {
+		mgr.debugContent("Role: %s, Content: %s", message.Role, message.Content)
+	}
+	mgr.messages = append(mgr.messages, messages...)
 
 	// NOTE Call Streamable Chat Completions Interface
-	assistantMessage := mgr.provider.CallStreamableChatCompletions(
-		mgr.messages,
-		&mgr.systemPrompt,
-	)
-	if assistantMessage == nil {
-		mgr.Errorf("failed to get response from provider")
-		return
+	assistantMessage, err := mgr.callProvider(ctx)
+	if err != nil {
+		mgr.Errorf("failed to get response from provider: %v", err)
+		return fmt.Errorf("failed to get response from provider: %w", err)
 	}
+
+	// NOTE Handle response with native tool calls
+	if len(assistantMessage.ToolCalls) > 0 {
+		return mgr.processToolCalls(ctx, turn, assistantMessage)
+	}
+
 	content := cast.ToString(assistantMessage.Content) // FIXME: 暂时强制转换到 string
 
 	// NOTE Handle response with tool use
 	plainContent, toolContent := mgr.splitContent(content)
-	mgr.Infof("plainContent: %s\r\ntoolContent: %s", plainContent, toolContent)
+	mgr.debugContent("plainContent: %s\r\ntoolContent: %v", plainContent, lo.FromPtr(toolContent))
 
 	// NOTE Check if the response contains tool use
 	if !mgr.containsToolUse(content) || toolContent == nil {
-		mgr.messages = append(mgr.messages, &Message{
-			Role:    RoleAssistant,
-			Content: content,
-		})
+		assistantMessage.Content = content
+		mgr.messages = append(mgr.messages, assistantMessage)
 
 		mgr.persistChat()
-		return
+		return nil
 	}
 
-	mgr.Infof("Assistant: %s\r\n, Tool: %s", plainContent, *toolContent)
+	mgr.debugContent("Assistant: %s\r\n, Tool: %s", plainContent, *toolContent)
 
 	MCPToolUse := mgr.MCPMgr.ExtractMCPToolUse(*toolContent)
 	if MCPToolUse == nil {
-		return
+		return fmt.Errorf("%w: %s", ErrInvalidToolUse, *toolContent)
 	}
 
 	// Add server, tool, and arguments info to assistant message
@@ -245,42 +287,235 @@ func (mgr *Manager) processUserMessage(turn *uint, message *Message) {
 	mgr.messages = append(mgr.messages, assistantMessage)
 
 	// Execute tool and get results
-	toolResults, err := mgr.MCPMgr.CallTool(context.Background(), toolName, args)
+	result, err := mgr.callTool(ctx, &ToolCallEvent{Server: svrName, Tool: toolName, Arguments: args})
 	if err != nil {
-		mgr.Errorf("failed to call tool: %v", err)
-		return
+		return err
 	}
 
-	if len(toolResults.Content) == 0 {
-		mgr.Errorf("no content in tool results")
-		return
+	// Create user message with tool results and include tool info
+	userMessage := NewMessageWithOption(
+		RoleTool,
+		result,
+		&MessageOption{
+			ID:       assistantMessage.ID,
+			Model:    assistantMessage.Model,
+			Provider: assistantMessage.Provider,
+
+			Server:    svrName,
+			Tool:      toolName,
+			Arguments: args,
+		})
+
+	// Process user message and assistant response recursively
+	(*turn)++
+	return mgr.processUserMessage(ctx, turn, userMessage)
+}
+
+// callProvider calls the provider with the messages of the chat. The MCP tools are passed through
+// native function calling if the provider supports it, unless the XML tool use protocol is configured.
+// If the provider fails with a rate limit, a timeout or a server error before streaming the answer,
+// the turn is retried with the fallbacks in order.
+func (mgr *Manager) callProvider(ctx context.Context) (*Message, error) {
+	chain := append([]*fallback{{provider: mgr.provider, config: mgr.config}}, mgr.fallbacks...)
+
+	for idx, current := range chain {
+		msg, streamed, err := mgr.callProviderOf(ctx, current.provider)
+		if err == nil {
+			// NOTE: Record the provider and the model which actually answered
+			msg.Provider = current.config.Provider
+			if msg.Model == "" {
+				msg.Model = current.config.Model
+			}
+			return msg, nil
+		}
+
+		if streamed || idx == len(chain)-1 || !canFallback(ctx, err) {
+			return nil, err
+		}
+
+		next := chain[idx+1].config
+		mgr.Warnf("%s/%s failed: %v, falling back to %s/%s",
+			current.config.Provider, current.config.Model, err, next.Provider, next.Model)
 	}
 
-	// TODO: Handle tool results
-	switch tc := toolResults.Content[0].(type) {
-	case *mcp.TextContent:
-		// Create user message with tool results and include tool info
-		userMessage := NewMessageWithOption(
+	return nil, ErrNoResponse
+}
+
+// callProviderOf calls the provider, reporting whether a part of the answer was streamed
+func (mgr *Manager) callProviderOf(ctx context.Context, provider Provider) (*Message, bool, error) {
+	// NOTE: The answer of a provider without text deltas is emitted as a whole
+	streamed := false
+	msg, err := mgr.doCallProvider(WithEventHandler(ctx, func(event *Event) {
+		streamed = streamed || event.Type == EventTextDelta
+		EmitEvent(ctx, event)
+	}), provider)
+	if err == nil && !streamed {
+		if content := cast.ToString(msg.Content); content != "" {
+			EmitEvent(ctx, &Event{Type: EventTextDelta, Delta: content})
+		}
+	}
+
+	return msg, streamed, err
+}
+
+func (mgr *Manager) doCallProvider(ctx context.Context, provider Provider) (*Message, error) {
+	// NOTE: The tool call mode follows each provider, a fallback without native function calling
+	// gets the XML tool use protocol in its system prompt
+	if caller, native := mgr.nativeToolCaller(provider); native {
+		return caller.CallStreamableChatCompletionsWithTools(
+			ctx,
+			mgr.messages,
+			mgr.systemPromptOf(ctx, true),
+			mgr.MCPMgr.Tools(ctx),
+		)
+	}
+
+	return provider.CallStreamableChatCompletions(
+		ctx,
+		mgr.messages,
+		mgr.systemPromptOf(ctx, false),
+	)
+}
+
+// nativeToolCaller returns the provider if the MCP tools are passed through native function calling
+func (mgr *Manager) nativeToolCaller(provider Provider) (NativeToolCaller, bool) {
+	if mgr.config.ToolCallMode == ToolCallModeXML {
+		return nil, false
+	}
+
+	caller, ok := provider.(NativeToolCaller)
+	return caller, ok
+}
+
+// systemPromptOf returns the system prompt of the turn, built once per tool call mode.
+// With native function calling the tools are sent in the request instead of the XML tool use protocol.
+func (mgr *Manager) systemPromptOf(ctx context.Context, native bool) *string {
+	if prompt, ok := mgr.systemPrompts[native]; ok {
+		return &prompt
+	}
+
+	// NOTE 1. Init basic system prompt
+	promptBuilder := strings.Builder{}
+	promptBuilder.WriteString(TimePrompt + "\n")
+
+	// NOTE 2. Initialize MCP and system prompt if MCP server settings exist
+	if mgr.MCPMgr != nil && !native {
+		promptBuilder.WriteString(
+			mgr.MCPMgr.Prompt(ctx, mgr.promptSvr) + "\n")
+	}
+
+	// NOTE 3. Initialize prompt
+	if mgr.promptSvr != nil {
+		// TODO: 后续使用配置，支持多个 prompt, e.g. "MCP", "Knowledge"
+		prompt := mgr.promptSvr.PromptByName(mgr.promptName)
+		if prompt != nil && !(native && prompt.Name == DefaultMCPPromptName) {
+			promptBuilder.WriteString(prompt.Content + "\n")
+		}
+	}
+
+	prompt := promptBuilder.String()
+	mgr.systemPrompts[native] = prompt
+	mgr.Debugf("System prompt: %s", prompt)
+
+	return &prompt
+}
+
+// debugContent logs the content of the conversation at debug level, only if debug_http is set
+func (mgr *Manager) debugContent(template string, args ...any) {
+	if mgr.config.DebugHTTP {
+		mgr.Debugf(template, args...)
+	}
+}
+
+// processToolCalls executes the native tool calls of the assistant message,
+// then sends one tool message per call back to the provider
+func (mgr *Manager) processToolCalls(ctx context.Context, turn *uint, assistantMessage *Message) error {
+	mgr.messages = append(mgr.messages, assistantMessage)
+
+	toolMessages := make([]*Message, 0, len(assistantMessage.ToolCalls))
+	for _, call := range assistantMessage.ToolCalls {
+		toolName := call.Function.Name
+		mgr.Infof("Tool call %s: %s", call.ID, toolName)
+		mgr.debugContent("Tool call %s arguments: %s", call.ID, call.Function.Arguments)
+
+		args := make(map[string]any)
+		if strings.TrimSpace(call.Function.Arguments) != "" {
+			if err := sonic.UnmarshalString(call.Function.Arguments, &args); err != nil {
+				return fmt.Errorf("%w: arguments of tool '%s': %w", ErrInvalidToolUse, toolName, err)
+			}
+		}
+
+		result, err := mgr.callTool(ctx, &ToolCallEvent{
+			ID:        call.ID,
+			Server:    mgr.MCPMgr.ToolServerName(toolName),
+			Tool:      toolName,
+			Arguments: args,
+		})
+		if err != nil {
+			return err
+		}
+
+		toolMessages = append(toolMessages, NewMessageWithOption(
 			RoleTool,
-			tc.Text,
+			result,
 			&MessageOption{
 				ID:       assistantMessage.ID,
 				Model:    assistantMessage.Model,
 				Provider: assistantMessage.Provider,
 
-				Server:    svrName,
-				Tool:      toolName,
-				Arguments: args,
-			})
+				Server:     mgr.MCPMgr.ToolServerName(toolName),
+				Tool:       toolName,
+				Arguments:  args,
+				ToolCallID: call.ID,
+			}))
+	}
+
+	// Process tool messages and assistant response recursively
+	(*turn)++
+	return mgr.processUserMessage(ctx, turn, toolMessages...)
+}
+
+// callTool calls the MCP tool and returns the text of its results, emitting the start and the result of the call
+func (mgr *Manager) callTool(ctx context.Context, call *ToolCallEvent) (string, error) {
+	EmitEvent(ctx, &Event{Type: EventToolCallStarted, ToolCall: call})
+
+	result, err := mgr.doCallTool(ctx, call.Tool, call.Arguments)
+	if err != nil {
+		return "", err
+	}
+
+	EmitEvent(ctx, &Event{Type: EventToolResult, ToolCall: &ToolCallEvent{
+		ID:        call.ID,
+		Server:    call.Server,
+		Tool:      call.Tool,
+		Arguments: call.Arguments,
+		Result:    result,
+	}})
+
+	return result, nil
+}
+
+func (mgr *Manager) doCallTool(ctx context.Context, toolName string, args map[string]any) (string, error) {
+	toolResults, err := mgr.MCPMgr.CallTool(ctx, toolName, args)
+	if err != nil {
+		mgr.Errorf("failed to call tool: %v", err)
+		return "", fmt.Errorf("failed to call tool '%s': %w", toolName, err)
+	}
+
+	if len(toolResults.Content) == 0 {
+		mgr.Errorf("no content in tool results")
+		return "", fmt.Errorf("no content in results of tool '%s'", toolName)
+	}
 
-		// Process user message and assistant response recursively
-		(*turn)++
-		mgr.processUserMessage(turn, userMessage)
+	// TODO: Handle tool results
+	switch tc := toolResults.Content[0].(type) {
+	case *mcp.TextContent:
+		return tc.Text, nil
 
 	default:
 		mgr.Errorf("unknown content type: %T", tc)
 
-		return
+		return "", fmt.Errorf("unknown content type %T in results of tool '%s'", tc, toolName)
 	}
 }
 
@@ -340,19 +575,116 @@ func (mgr *Manager) splitContent(content string) (string, *string) {
 	return strings.TrimSpace(content), nil
 }
 
-func (mgr *Manager) loadChat(ctx context.Context) {
+// ChatID returns the ID of the current chat
+func (mgr *Manager) ChatID() string { return mgr.chatID }
+
+// ChatSvr returns the chat service backing the manager
+func (mgr *Manager) ChatSvr() *ChatSvr { return mgr.chatSvr }
+
+// PromptSvr returns the prompt service backing the manager
+func (mgr *Manager) PromptSvr() *PromptSvr { return mgr.promptSvr }
+
+// Model returns the model used for the next turn
+func (mgr *Manager) Model() string { return mgr.config.Model }
+
+// SetModel switches the model used for the next turn
+func (mgr *Manager) SetModel(model string) {
+	mgr.Infof("switch model from %s to %s", mgr.config.Model, model)
+	mgr.config.Model = model
+	mgr.modelOverride = model
+}
+
+// Profile returns the profile used for the next turn, empty if none
+func (mgr *Manager) Profile() string { return mgr.config.Profile() }
+
+// Profiles returns the sorted names of the profiles of the config
+func (mgr *Manager) Profiles() []string { return mgr.config.ProfileNames() }
+
+// SetProfile switches the provider and the model to the profile for the next turn,
+// the default profile if name is empty. The chat goes on with the messages so far.
+func (mgr *Manager) SetProfile(name string) error {
+	config, err := mgr.config.WithProfile(name)
+	if err != nil {
+		return err
+	}
+
+	provider, fallbacks, err := newProviderChain(config, mgr.Logger)
+	if err != nil {
+		return fmt.Errorf("profile '%s': %w", config.Profile(), err)
+	}
+
+	mgr.Infof("switch profile from '%s' to '%s' (%s/%s)",
+		mgr.config.Profile(), config.Profile(), config.Provider, config.Model)
+	mgr.config, mgr.provider, mgr.fallbacks = config, provider, fallbacks
+	mgr.modelOverride = ""
+
+	return nil
+}
+
+// PromptName returns the name of the prompt appended to the system prompt
+func (mgr *Manager) PromptName() string { return mgr.promptName }
+
+// SetPrompt switches the prompt appended to the system prompt, which must exist
+func (mgr *Manager) SetPrompt(name string) error {
+	if mgr.promptSvr.PromptByName(name) == nil {
+		return fmt.Errorf("prompt '%s' not found", name)
+	}
+
+	mgr.Infof("switch prompt from %s to %s", mgr.promptName, name)
+	mgr.promptName = name
+
+	return nil
+}
+
+// NewChat starts a new empty chat and returns its ID
+func (mgr *Manager) NewChat() string {
+	mgr.chat = nil
+	mgr.messages = make([]*Message, 0, DefaultChatMessageSize)
+	mgr.chatID = GenerateChatID()
+	mgr.continueExist = false
+
+	mgr.Info("new chat created, chat id: ", mgr.chatID)
+
+	return mgr.chatID
+}
+
+// LoadChat switches to the existing chat with the specified ID
+func (mgr *Manager) LoadChat(ctx context.Context, chatID string) (*Chat, error) {
+	chat, err := mgr.chatSvr.Chat(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chat %s: %w", chatID, err)
+	}
+	if chat == nil {
+		return nil, fmt.Errorf("chat %s not found", chatID)
+	}
+
+	mgr.chatID = chatID
+	mgr.continueExist = true
+	mgr.chat = chat
+	mgr.messages = chat.Messages
+
+	mgr.Infof("Loaded %d messages from chat %s", len(mgr.messages), mgr.chatID)
+
+	return chat, nil
+}
+
+func (mgr *Manager) loadChat(ctx context.Context) error {
 	chat, err := mgr.chatSvr.Chat(ctx, mgr.chatID)
 	if err != nil {
 		mgr.Errorf("failed to load chat: %v", err)
-		// TODO: 输出到前端，例如控制台
-
-		return
+		return fmt.Errorf("failed to load chat %s: %w", mgr.chatID, err)
+	}
+	if chat == nil {
+		mgr.Errorf("chat %s not found", mgr.chatID)
+		return fmt.Errorf("chat %s not found", mgr.chatID)
 	}
 
 	mgr.messages = chat.Messages
 	mgr.chat = chat
 
 	mgr.Infof("Loaded %d messages from chat %s", len(mgr.messages), mgr.chatID)
+
+	return nil
 }
 
 func (mgr *Manager) persistChat() {
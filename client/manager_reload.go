@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+)
+
+// Watch watches client.yaml, mcp_servers.jsonl and prompts.jsonl until ctx is done.
+// A changed file is reloaded at the start of the next turn, never during a turn: the changed MCP servers
+// are reconnected, the prompts reloaded and client.yaml applied with the current profile and /model.
+// The next turn starts with an EventReloaded per reloaded file, so that the system prompt is rebuilt.
+// The storage and the paths of the files are not reloaded.
+func (mgr *Manager) Watch(ctx context.Context) error {
+	watcher, err := NewFileWatcher(DefaultReloadDelay, mgr.Logger)
+	if err != nil {
+		return err
+	}
+
+	watch := func(path string, reload func() *ReloadEvent) error {
+		if err := watcher.Add(path, func() { mgr.queueReload(path, reload) }); err != nil {
+			_ = watcher.watcher.Close()
+			return err
+		}
+		return nil
+	}
+
+	if repo, ok := mgr.MCPMgr.repo.(ReloadableRepo); ok {
+		if err := watch(repo.Path(), func() *ReloadEvent { return mgr.reloadMCPServers(ctx, repo) }); err != nil {
+			return err
+		}
+	}
+	if repo, ok := mgr.promptSvr.repo.(ReloadableRepo); ok {
+		if err := watch(repo.Path(), func() *ReloadEvent { return mgr.reloadPrompts(repo) }); err != nil {
+			return err
+		}
+	}
+	if path := mgr.config.Path(); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			if err := watch(path, func() *ReloadEvent { return mgr.reloadConfig(path) }); err != nil {
+				return err
+			}
+		}
+	}
+
+	go watcher.Run(ctx)
+
+	return nil
+}
+
+// reloadMCPServers reloads mcp_servers.jsonl and reconnects the changed MCP servers
+func (mgr *Manager) reloadMCPServers(ctx context.Context, repo ReloadableRepo) *ReloadEvent {
+	event := &ReloadEvent{Path: repo.Path()}
+	if event.Err = repo.Reload(); event.Err == nil {
+		event.Servers = mgr.MCPMgr.Reload(ctx)
+		mgr.Infof("MCP servers reloaded, reconnected: %v", event.Servers)
+	}
+
+	return event
+}
+
+// reloadPrompts reloads prompts.jsonl
+func (mgr *Manager) reloadPrompts(repo ReloadableRepo) *ReloadEvent {
+	return &ReloadEvent{Path: repo.Path(), Err: repo.Reload()}
+}
+
+// reloadConfig loads client.yaml and switches to it
+func (mgr *Manager) reloadConfig(path string) *ReloadEvent {
+	config, err := NewConfigFromFile(path, mgr.Logger)
+	if err == nil {
+		err = mgr.applyConfig(config)
+	}
+	if err != nil {
+		mgr.Errorf("failed to reload %s: %v", path, err)
+	}
+
+	return &ReloadEvent{Path: path, Err: err}
+}
+
+// queueReload queues the reload of the changed file for the next turn, once however often the file changed.
+// It is called by the watcher goroutine, which must not touch the state used by a turn in progress.
+func (mgr *Manager) queueReload(path string, reload func() *ReloadEvent) {
+	mgr.reloadMu.Lock()
+	defer mgr.reloadMu.Unlock()
+
+	if mgr.reloads == nil {
+		mgr.reloads = make(map[string]func() *ReloadEvent)
+	}
+	mgr.reloads[path] = reload
+}
+
+// applyReloads reloads the files changed since the last turn and emits their events, at the start of a turn
+func (mgr *Manager) applyReloads(ctx context.Context) {
+	mgr.reloadMu.Lock()
+	reloads := mgr.reloads
+	mgr.reloads = nil
+	mgr.reloadMu.Unlock()
+
+	for _, path := range slices.Sorted(maps.Keys(reloads)) {
+		EmitEvent(ctx, &Event{Type: EventReloaded, Reload: reloads[path]()})
+	}
+}
+
+// applyConfig switches to the reloaded config, keeping the current profile and the model set by SetModel
+func (mgr *Manager) applyConfig(root *Config) error {
+	config, err := root.WithProfile(mgr.config.Profile())
+	if err != nil {
+		return err
+	}
+	if mgr.modelOverride != "" {
+		config.Model = mgr.modelOverride
+	}
+
+	provider, fallbacks, err := newProviderChain(config, mgr.Logger)
+	if err != nil {
+		return fmt.Errorf("profile '%s': %w", config.Profile(), err)
+	}
+
+	mgr.Infof("config reloaded from %s (%s/%s)", config.Path(), config.Provider, config.Model)
+	mgr.config, mgr.provider, mgr.fallbacks = config, provider, fallbacks
+
+	return nil
+}
@@ -0,0 +1,742 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cast"
+)
+
+// scriptedStub is a provider server answering the n-th request with the n-th response.
+// The requests of the OpenAI and Ollama providers are both decoded as OpenAIChatRequest.
+type scriptedStub struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*OpenAIChatRequest
+}
+
+// newScriptedOpenAIStub returns a server streaming each response as SSE events, one per data payload
+func newScriptedOpenAIStub(t *testing.T, responses ...[]string) *scriptedStub {
+	return newScriptedStub(t, true, responses...)
+}
+
+// newScriptedOllamaStub returns a server streaming each response as one JSON object per line
+func newScriptedOllamaStub(t *testing.T, responses ...[]string) *scriptedStub {
+	return newScriptedStub(t, false, responses...)
+}
+
+func newScriptedStub(t *testing.T, sse bool, responses ...[]string) *scriptedStub {
+	stub := &scriptedStub{}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := &OpenAIChatRequest{}
+		if err := sonic.Unmarshal(body, request); err != nil {
+			t.Errorf("failed to parse request: %v", err)
+		}
+
+		stub.mu.Lock()
+		idx := len(stub.requests)
+		stub.requests = append(stub.requests, request)
+		stub.mu.Unlock()
+
+		if idx >= len(responses) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if !sse {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			for _, data := range responses[idx] {
+				fmt.Fprintln(w, data)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range responses[idx] {
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(stub.Close)
+
+	return stub
+}
+
+// newTestManager returns a manager talking to the provider at baseURL, with the test MCP server connected
+func newTestManager(t *testing.T, provider, baseURL, toolCallMode string) *Manager {
+	return newTestManagerWithConfig(t, &Config{
+		Provider:      provider,
+		BaseURL:       baseURL,
+		CustomAPIPath: DefaultCustomAPIPath,
+		Model:         "test-model",
+		Stream:        true,
+		MaxTurns:      3,
+		ToolCallMode:  toolCallMode,
+	})
+}
+
+// newTestManagerWithConfig returns a manager with the config, with the test MCP server connected
+func newTestManagerWithConfig(t *testing.T, config *Config) *Manager {
+	dir := t.TempDir()
+	logger := &discardLogger{}
+
+	chatRepo, err := NewChatFileRepository(filepath.Join(dir, "chats.jsonl"), 2, logger)
+	if err != nil {
+		t.Fatalf("failed to create chat repository: %v", err)
+	}
+	t.Cleanup(func() { _ = chatRepo.Close() })
+
+	mcpRepo, err := NewMCPSvrConfigFileRepo(filepath.Join(dir, "mcp_servers.jsonl"), logger)
+	if err != nil {
+		t.Fatalf("failed to create MCP server repository: %v", err)
+	}
+	if err := mcpRepo.UpdateMCPServerConfigByName(&MCPSvrItem{
+		Name:     "test",
+		Type:     ServerTypeStreamableHTTP,
+		IsActive: true,
+		BaseURL:  newTestMCPServer(t).URL,
+	}); err != nil {
+		t.Fatalf("failed to add MCP server: %v", err)
+	}
+
+	promptRepo, err := NewPromptFileRepo(filepath.Join(dir, "prompts.jsonl"), logger)
+	if err != nil {
+		t.Fatalf("failed to create prompt repository: %v", err)
+	}
+
+	mgr, err := NewManager(logger, chatRepo, mcpRepo, promptRepo, nil, config)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	t.Cleanup(mgr.MCPMgr.ClossAllSession)
+
+	return mgr
+}
+
+func openAIContentChunk(content, finishReason string) string {
+	return fmt.Sprintf(`{"id":"resp","model":"test-model",`+
+		`"choices":[{"index":0,"delta":{"content":%q},"finish_reason":%q}]}`, content, finishReason)
+}
+
+func TestManager_NativeToolCall(t *testing.T) {
+	stub := newScriptedOpenAIStub(t,
+		[]string{
+			`{"id":"resp-1","model":"test-model","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":` +
+				`[{"index":0,"id":"call_1","type":"function","function":{"name":"echo","arguments":""}}]}}]}`,
+			`{"id":"resp-1","model":"test-model","choices":[{"index":0,"delta":{"tool_calls":` +
+				`[{"index":0,"function":{"arguments":"{\"text\":"}}]}}]}`,
+			`{"id":"resp-1","model":"test-model","choices":[{"index":0,"delta":{"tool_calls":` +
+				`[{"index":0,"function":{"arguments":"\"hi\"}"}}]}}]}`,
+			`{"id":"resp-1","model":"test-model","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		},
+		[]string{openAIContentChunk("The tool said ", ""), openAIContentChunk("echo: hi", "stop")},
+	)
+	mgr := newTestManager(t, ProviderOpenAI, stub.URL, ToolCallModeNative)
+
+	msg, err := mgr.HandleUserTextInput(t.Context(), "echo hi")
+	if err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+	if got := cast.ToString(msg.Content); got != "The tool said echo: hi" {
+		t.Errorf("HandleUserTextInput() content = %q, want %q", got, "The tool said echo: hi")
+	}
+
+	if len(stub.requests) != 2 {
+		t.Fatalf("provider got %d requests, want 2", len(stub.requests))
+	}
+
+	// NOTE: The tools are sent natively instead of the XML tool use protocol
+	first := stub.requests[0]
+	if len(first.Tools) != 2 || first.Tools[0].Function.Name != "echo" || first.Tools[0].Function.Parameters == nil {
+		t.Errorf("first request tools = %+v, want the tools of the test server with their schema", first.Tools)
+	}
+	if system := cast.ToString(first.Messages[0]["content"]); strings.Contains(system, "use_mcp_tool") {
+		t.Errorf("system prompt should not describe the XML tool use protocol in native mode")
+	}
+
+	// NOTE: The tool result is sent back with the ID of the tool call
+	second := stub.requests[1].Messages
+	assistant, tool := second[len(second)-2], second[len(second)-1]
+	if assistant["role"] != RoleAssistant || assistant["tool_calls"] == nil {
+		t.Errorf("second request should contain the assistant tool calls, got %v", assistant)
+	}
+	if tool["role"] != RoleTool || tool["tool_call_id"] != "call_1" || tool["content"] != "echo: hi" {
+		t.Errorf("second request should end with the tool result, got %v", tool)
+	}
+
+	// NOTE: The history keeps the tool call and its result
+	if len(mgr.messages) != 4 {
+		t.Fatalf("history has %d messages, want 4", len(mgr.messages))
+	}
+	calls := mgr.messages[1].ToolCalls
+	if len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Function.Arguments != `{"text":"hi"}` {
+		t.Errorf("assistant tool calls = %+v, want call_1 echo({\"text\":\"hi\"})", calls)
+	}
+	if result := mgr.messages[2]; result.ToolCallID != "call_1" || result.Server != "test" || result.Tool != "echo" {
+		t.Errorf("tool message = %+v, want call_1 test/echo", result)
+	}
+}
+
+func TestManager_Reasoning(t *testing.T) {
+	stub := newScriptedOpenAIStub(t,
+		[]string{
+			`{"id":"resp-1","model":"test-model","choices":[{"index":0,"delta":{"reasoning_content":"Greet "}}]}`,
+			`{"id":"resp-1","model":"test-model","choices":[{"index":0,"delta":{"reasoning":"back."}}]}`,
+			openAIContentChunk("Hello!", "stop"),
+		},
+		[]string{openAIContentChunk("Bye!", "stop")},
+	)
+	mgr := newTestManager(t, ProviderOpenAI, stub.URL, ToolCallModeNative)
+
+	msg, err := mgr.HandleUserTextInput(t.Context(), "hi")
+	if err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+	if msg.Content != "Hello!" || msg.ReasoningContent != "Greet back." || msg.Reasoning() != "Greet back." {
+		t.Errorf("message content = %q, reasoning = %q, want the answer and the reasoning apart",
+			msg.Content, msg.ReasoningContent)
+	}
+
+	// NOTE: Only the answer is sent back in the history
+	if _, err := mgr.HandleUserTextInput(t.Context(), "bye"); err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+	data, _ := sonic.MarshalString(stub.requests[1].Messages)
+	if strings.Contains(data, "Greet") || !strings.Contains(data, "Hello!") {
+		t.Errorf("second request messages = %s, want the answer without the reasoning", data)
+	}
+
+	// NOTE: The chats saved by the previous versions duplicate the answer as the reasoning
+	legacy := NewMessageWithOption(RoleAssistant, "Hello!", &MessageOption{ReasoningContent: "Hello!"})
+	if legacy.Reasoning() != "" {
+		t.Errorf("Reasoning() = %q, want empty for a legacy message", legacy.Reasoning())
+	}
+}
+
+func TestManager_Usage(t *testing.T) {
+	stub := newScriptedOpenAIStub(t, []string{
+		openAIContentChunk("Hello!", "stop"),
+		`{"id":"resp","model":"test-model","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":34,` +
+			`"completion_tokens_details":{"reasoning_tokens":5}}}`,
+	})
+	mgr := newTestManager(t, ProviderOpenAI, stub.URL, ToolCallModeNative)
+
+	msg, err := mgr.HandleUserTextInput(t.Context(), "hi")
+	if err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+	if options := stub.requests[0].StreamOptions; options == nil || !options.IncludeUsage {
+		t.Errorf("stream_options = %+v, want include_usage", options)
+	}
+	want := Usage{PromptTokens: 12, CompletionTokens: 34, ReasoningTokens: 5}
+	if msg.Usage == nil || *msg.Usage != want {
+		t.Errorf("usage = %+v, want %+v", msg.Usage, want)
+	}
+
+	// NOTE: The usage is kept in the history and the /usage command sums it
+	out := &strings.Builder{}
+	if err := NewCommandRegistry().Execute(t.Context(), mgr, "/usage", out); err != nil {
+		t.Fatalf("Execute(/usage) error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Current chat: 1 answers, 12 prompt + 34 completion tokens (5 reasoning)") {
+		t.Errorf("Execute(/usage) = %q, want the usage of the current chat", out.String())
+	}
+	if today := time.Now().Format(UsageDayLayout); !strings.Contains(out.String(), today+"  1 answers") {
+		t.Errorf("Execute(/usage) = %q, want the usage of today", out.String())
+	}
+}
+
+func TestManager_OllamaUsage(t *testing.T) {
+	stub := newScriptedOllamaStub(t, []string{
+		`{"model":"test-model","message":{"role":"assistant","content":"Hello!"},"done":false}`,
+		`{"model":"test-model","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop",` +
+			`"total_duration":2000000000,"load_duration":100,"prompt_eval_count":12,"prompt_eval_duration":200,` +
+			`"eval_count":34,"eval_duration":1000000000}`,
+	})
+	mgr := newTestManager(t, ProviderOllama, stub.URL, ToolCallModeNative)
+
+	msg, err := mgr.HandleUserTextInput(t.Context(), "hi")
+	if err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+	want := Usage{
+		PromptTokens:       12,
+		CompletionTokens:   34,
+		TotalDuration:      2 * time.Second,
+		LoadDuration:       100,
+		PromptEvalDuration: 200,
+		EvalDuration:       time.Second,
+	}
+	if msg.Usage == nil || *msg.Usage != want {
+		t.Errorf("usage = %+v, want %+v", msg.Usage, want)
+	}
+}
+
+func TestManager_Fallback(t *testing.T) {
+	primary := newScriptedOpenAIStub(t) // NOTE: Always 500
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	fallback := newScriptedOpenAIStub(t, []string{
+		`{"id":"resp","choices":[{"index":0,"delta":{"content":"Hello!"},"finish_reason":"stop"}]}`,
+	})
+
+	mgr := newTestManagerWithConfig(t, &Config{
+		Provider:      ProviderTaiji,
+		BaseURL:       primary.URL,
+		CustomAPIPath: DefaultCustomAPIPath,
+		Model:         "test-model",
+		APIKey:        "primary-key",
+		Stream:        true,
+		MaxTurns:      3,
+		ToolCallMode:  ToolCallModeNative,
+		Fallbacks: []*FallbackConfig{
+			{Provider: ProviderOllama, BaseURL: down.URL, Model: "llama3.1"},
+			{Provider: ProviderOpenAI, BaseURL: fallback.URL, Model: "fallback-model"},
+		},
+	})
+
+	msg, err := mgr.HandleUserTextInput(t.Context(), "hi")
+	if err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+	if msg.Content != "Hello!" || msg.Provider != ProviderOpenAI || msg.Model != "fallback-model" {
+		t.Errorf("message = %s/%s %q, want the answer of the last fallback", msg.Provider, msg.Model, msg.Content)
+	}
+	if len(primary.requests) != 1 || len(fallback.requests) != 1 {
+		t.Errorf("requests = %d, %d, want one to the primary and one to the fallback",
+			len(primary.requests), len(fallback.requests))
+	}
+
+	// NOTE: The API key of the primary provider is not sent to another provider
+	if configs := mgr.config.FallbackConfigs(); configs[1].APIKey != "" || configs[1].CustomAPIPath != "" {
+		t.Errorf("fallback config = %+v, want the endpoint and the API key of the primary dropped", configs[1])
+	}
+}
+
+func TestManager_FallbackToolCallMode(t *testing.T) {
+	primary := newScriptedOpenAIStub(t) // NOTE: Always 500
+	fallback := newScriptedOpenAIStub(t, []string{openAIContentChunk("Hello!", "stop")})
+
+	mgr := newTestManagerWithConfig(t, &Config{
+		Provider:      ProviderOpenAI,
+		BaseURL:       primary.URL,
+		CustomAPIPath: DefaultCustomAPIPath,
+		Model:         "test-model",
+		Stream:        true,
+		MaxTurns:      3,
+		ToolCallMode:  ToolCallModeNative,
+		Fallbacks:     []*FallbackConfig{{Provider: ProviderTaiji, BaseURL: fallback.URL, APIKey: "taiji-key"}},
+	})
+
+	if _, err := mgr.HandleUserTextInput(t.Context(), "hi"); err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+	if len(primary.requests) != 1 || len(fallback.requests) != 1 {
+		t.Fatalf("requests = %d, %d, want one to each provider", len(primary.requests), len(fallback.requests))
+	}
+
+	// NOTE: The primary gets the tools natively, the fallback without function calling gets the XML protocol
+	first := primary.requests[0]
+	if len(first.Tools) == 0 || strings.Contains(cast.ToString(first.Messages[0]["content"]), "use_mcp_tool") {
+		t.Errorf("primary request = %+v, want the native tools without the XML tool use protocol", first)
+	}
+	second := fallback.requests[0]
+	if len(second.Tools) != 0 || !strings.Contains(cast.ToString(second.Messages[0]["content"]), "use_mcp_tool") {
+		t.Errorf("fallback request = %+v, want the XML tool use protocol in the system prompt", second)
+	}
+}
+
+func TestManager_SetProfile(t *testing.T) {
+	primary := newScriptedOpenAIStub(t, []string{openAIContentChunk("Hello from primary!", "stop")})
+	local := newScriptedOllamaStub(t, []string{
+		`{"message":{"role":"assistant","content":"Hello from local!"},"done":true,"done_reason":"stop"}`,
+	})
+
+	config := &Config{
+		Provider:      ProviderOpenAI,
+		BaseURL:       primary.URL,
+		CustomAPIPath: DefaultCustomAPIPath,
+		Model:         "test-model",
+		APIKey:        "primary-key",
+		Stream:        true,
+		MaxTurns:      3,
+		ToolCallMode:  ToolCallModeNative,
+		Profiles: map[string]*ProfileConfig{
+			"local": {Provider: ProviderOllama, BaseURL: local.URL, CustomAPIPath: "/api/chat", Model: "llama3.1"},
+		},
+	}
+	config, err := config.WithProfile("")
+	if err != nil {
+		t.Fatalf("WithProfile() error = %v", err)
+	}
+	mgr := newTestManagerWithConfig(t, config)
+
+	if _, err := mgr.HandleUserTextInput(t.Context(), "hi"); err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+	if err := mgr.SetProfile("LOCAL"); err != nil {
+		t.Fatalf("SetProfile() error = %v", err)
+	}
+	msg, err := mgr.HandleUserTextInput(t.Context(), "hi again")
+	if err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+
+	// NOTE: Each answer records the provider and the model of the profile used
+	if msg.Content != "Hello from local!" || msg.Provider != ProviderOllama || msg.Model != "llama3.1" {
+		t.Errorf("message = %s/%s %q, want the answer of the local profile", msg.Provider, msg.Model, msg.Content)
+	}
+	if first := mgr.messages[1]; first.Provider != ProviderOpenAI || first.Model != "test-model" {
+		t.Errorf("first answer = %s/%s, want the primary provider", first.Provider, first.Model)
+	}
+	if len(local.requests) != 1 || len(local.requests[0].Messages) != 4 {
+		t.Errorf("local requests = %+v, want the chat so far", local.requests)
+	}
+	if mgr.Profile() != "local" || mgr.config.APIKey != "" {
+		t.Errorf("profile = %q with API key %q, want local without the key of the primary",
+			mgr.Profile(), mgr.config.APIKey)
+	}
+
+	// NOTE: The empty profile switches back to the top level config
+	if err := mgr.SetProfile(""); err != nil || mgr.Profile() != "" || mgr.Model() != "test-model" {
+		t.Errorf("SetProfile(\"\") = %v, profile %q, model %s, want the top level config",
+			err, mgr.Profile(), mgr.Model())
+	}
+	if err := mgr.SetProfile("remote"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("SetProfile(remote) error = %v, want ErrUnknownProfile", err)
+	}
+}
+
+func TestManager_XMLToolUse(t *testing.T) {
+	toolUse := "Let me call the tool.\n<use_mcp_tool>\n<server_name>test</server_name>\n" +
+		"<tool_name>echo</tool_name>\n<arguments>\n{\"text\": \"hi\"}\n</arguments>\n</use_mcp_tool>"
+	stub := newScriptedOpenAIStub(t,
+		[]string{openAIContentChunk(toolUse, "stop")},
+		[]string{openAIContentChunk("The tool said echo: hi", "stop")},
+	)
+	mgr := newTestManager(t, ProviderOpenAI, stub.URL, ToolCallModeXML)
+
+	msg, err := mgr.HandleUserTextInput(t.Context(), "echo hi")
+	if err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+	if got := cast.ToString(msg.Content); got != "The tool said echo: hi" {
+		t.Errorf("HandleUserTextInput() content = %q, want %q", got, "The tool said echo: hi")
+	}
+
+	if len(stub.requests) != 2 {
+		t.Fatalf("provider got %d requests, want 2", len(stub.requests))
+	}
+	if len(stub.requests[0].Tools) != 0 {
+		t.Errorf("tools should not be sent in XML mode, got %+v", stub.requests[0].Tools)
+	}
+	if system := cast.ToString(stub.requests[0].Messages[0]["content"]); !strings.Contains(system, "use_mcp_tool") {
+		t.Errorf("system prompt should describe the XML tool use protocol in XML mode")
+	}
+
+	second := stub.requests[1].Messages
+	if tool := second[len(second)-1]; tool["role"] != RoleTool || tool["content"] != "echo: hi" {
+		t.Errorf("second request should end with the tool result, got %v", tool)
+	}
+}
+
+func TestManager_OllamaNativeToolCall(t *testing.T) {
+	stub := newScriptedOllamaStub(t,
+		[]string{
+			`{"model":"test-model","message":{"role":"assistant","content":"",` +
+				`"tool_calls":[{"function":{"name":"echo","arguments":{"text":"hi"}}}]},"done":false}`,
+			`{"model":"test-model","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop"}`,
+		},
+		[]string{
+			`{"model":"test-model","message":{"role":"assistant","content":"The tool said "},"done":false}`,
+			`{"model":"test-model","message":{"role":"assistant","content":"echo: hi"},"done":false}`,
+			`{"model":"test-model","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop"}`,
+		},
+	)
+	mgr := newTestManager(t, ProviderOllama, stub.URL, ToolCallModeNative)
+
+	msg, err := mgr.HandleUserTextInput(t.Context(), "echo hi")
+	if err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+	if got := cast.ToString(msg.Content); got != "The tool said echo: hi" {
+		t.Errorf("HandleUserTextInput() content = %q, want %q", got, "The tool said echo: hi")
+	}
+
+	if len(stub.requests) != 2 {
+		t.Fatalf("provider got %d requests, want 2", len(stub.requests))
+	}
+	if tools := stub.requests[0].Tools; len(tools) != 2 || tools[0].Function.Name != "echo" {
+		t.Errorf("first request tools = %+v, want the tools of the test server", tools)
+	}
+
+	// NOTE: Ollama expects the arguments of the tool calls as JSON objects
+	second := stub.requests[1].Messages
+	assistant, tool := second[len(second)-2], second[len(second)-1]
+	calls, _ := assistant["tool_calls"].([]any)
+	if len(calls) != 1 {
+		t.Fatalf("second request should contain the assistant tool call, got %v", assistant)
+	}
+	function, _ := calls[0].(map[string]any)["function"].(map[string]any)
+	if args, ok := function["arguments"].(map[string]any); !ok || args["text"] != "hi" {
+		t.Errorf("tool call arguments = %v, want {\"text\": \"hi\"}", function["arguments"])
+	}
+	if tool["role"] != RoleTool || tool["tool_name"] != "echo" || tool["content"] != "echo: hi" {
+		t.Errorf("second request should end with the tool result, got %v", tool)
+	}
+}
+
+func TestManager_OllamaError(t *testing.T) {
+	stub := newScriptedOllamaStub(t, []string{`{"error":"model 'test-model' not found"}`})
+	mgr := newTestManager(t, ProviderOllama, stub.URL, ToolCallModeNative)
+
+	if _, err := mgr.HandleUserTextInput(t.Context(), "hi"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("HandleUserTextInput() error = %v, want the Ollama error", err)
+	}
+}
+
+func TestManager_CancelStream(t *testing.T) {
+	// NOTE: The provider streams one chunk, then hangs until the request is aborted
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\n", openAIContentChunk("Thinking", ""))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(stub.Close)
+	mgr := newTestManager(t, ProviderOpenAI, stub.URL, ToolCallModeNative)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := mgr.HandleUserTextInput(ctx, "hi")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("HandleUserTextInput() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("HandleUserTextInput() returned after %s, want it aborted at the deadline", elapsed)
+	}
+	if len(mgr.messages) != 0 {
+		t.Errorf("messages = %d, want the messages of the aborted turn dropped", len(mgr.messages))
+	}
+}
+
+func TestManager_CancelToolCall(t *testing.T) {
+	stub := newScriptedOpenAIStub(t, []string{
+		`{"id":"resp-1","model":"test-model","choices":[{"index":0,"delta":{"tool_calls":` +
+			`[{"index":0,"id":"call_1","type":"function","function":{"name":"wait","arguments":"{}"}}]},` +
+			`"finish_reason":"tool_calls"}]}`,
+	})
+	mgr := newTestManager(t, ProviderOpenAI, stub.URL, ToolCallModeNative)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := mgr.HandleUserTextInput(ctx, "wait")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("HandleUserTextInput() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("HandleUserTextInput() returned after %s, want the tool call aborted", elapsed)
+	}
+	if len(stub.requests) != 1 {
+		t.Errorf("provider got %d requests, want 1", len(stub.requests))
+	}
+}
+
+func TestManager_StreamUserTextInput(t *testing.T) {
+	stub := newScriptedOpenAIStub(t,
+		[]string{
+			`{"id":"resp-1","model":"test-model","choices":[{"index":0,"delta":{"tool_calls":` +
+				`[{"index":0,"id":"call_1","type":"function","function":{"name":"echo","arguments":"{\"text\":\"hi\"}"}}]},` +
+				`"finish_reason":"tool_calls"}]}`,
+		},
+		[]string{openAIContentChunk("The tool said ", ""), openAIContentChunk("echo: hi", "stop")},
+	)
+	mgr := newTestManager(t, ProviderOpenAI, stub.URL, ToolCallModeNative)
+
+	var (
+		types []EventType
+		text  strings.Builder
+		last  *Event
+	)
+	for event := range mgr.StreamUserTextInput(t.Context(), "echo hi") {
+		types = append(types, event.Type)
+		if event.Type == EventTextDelta {
+			text.WriteString(event.Delta)
+		}
+		if event.Type == EventToolResult && event.ToolCall.Result != "echo: hi" {
+			t.Errorf("tool result = %+v, want echo: hi", event.ToolCall)
+		}
+		last = event
+	}
+
+	want := []EventType{EventToolCallStarted, EventToolResult, EventTextDelta, EventTextDelta, EventTurnFinished}
+	if !slices.Equal(types, want) {
+		t.Errorf("event types = %v, want %v", types, want)
+	}
+	if text.String() != "The tool said echo: hi" {
+		t.Errorf("text deltas = %q, want the answer", text.String())
+	}
+	if last.Message == nil || last.Message.Content != "The tool said echo: hi" {
+		t.Errorf("turn finished message = %+v, want the answer", last.Message)
+	}
+}
+
+func TestManager_StreamUserTextInputError(t *testing.T) {
+	stub := newScriptedOpenAIStub(t)
+	mgr := newTestManager(t, ProviderOpenAI, stub.URL, ToolCallModeNative)
+
+	var events []*Event
+	for event := range mgr.StreamUserTextInput(t.Context(), "hi") {
+		events = append(events, event)
+	}
+
+	if len(events) != 1 || events[0].Type != EventError || events[0].Err == nil {
+		t.Errorf("events = %+v, want one error event", events)
+	}
+}
+
+func TestManager_MissingChat(t *testing.T) {
+	stub := newScriptedOpenAIStub(t)
+	mgr := newTestManager(t, ProviderOpenAI, stub.URL, ToolCallModeNative)
+	mgr.chatID, mgr.continueExist = "missing", true
+
+	if _, err := mgr.HandleUserTextInput(t.Context(), "hi"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("HandleUserTextInput() error = %v, want chat missing not found", err)
+	}
+
+	var events []*Event
+	for event := range mgr.StreamUserTextInput(t.Context(), "hi") {
+		events = append(events, event)
+	}
+	if len(events) != 1 || events[0].Type != EventError || events[0].Err == nil {
+		t.Errorf("events = %+v, want one error event", events)
+	}
+
+	if _, err := mgr.LoadChat(t.Context(), "missing"); err == nil {
+		t.Error("LoadChat() of a missing chat should fail")
+	}
+}
+
+func TestManager_Watch(t *testing.T) {
+	stub := newScriptedOpenAIStub(t, []string{openAIContentChunk("hello", "stop")})
+	mgr := newTestManager(t, ProviderOpenAI, stub.URL, ToolCallModeNative)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	if err := mgr.Watch(ctx); err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	// NOTE: Change the files like another k-cli process would
+	mcpRepo, err := NewMCPSvrConfigFileRepo(mgr.MCPMgr.repo.(ReloadableRepo).Path(), &discardLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, _ := mcpRepo.MCPServerConfigByName("test")
+	disabled := *item
+	disabled.IsActive = false
+	if err := mcpRepo.UpdateMCPServerConfigByName(&disabled); err != nil {
+		t.Fatal(err)
+	}
+
+	promptRepo, err := NewPromptFileRepo(mgr.promptSvr.repo.(ReloadableRepo).Path(), &discardLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := promptRepo.UpdatePromptByName(&PromptItem{Name: "reloaded", Content: "reloaded"}); err != nil {
+		t.Fatal(err)
+	}
+
+	queued := func() int {
+		mgr.reloadMu.Lock()
+		defer mgr.reloadMu.Unlock()
+		return len(mgr.reloads)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for queued() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("files not queued for reload, %d queued", queued())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// NOTE: The files are reloaded at the start of the next turn, not by the watcher
+	if len(mgr.MCPMgr.MCPServerList()) != 1 || mgr.PromptSvr().PromptByName("reloaded") != nil {
+		t.Fatalf("files reloaded before the next turn, servers = %v", mgr.MCPMgr.MCPServerList())
+	}
+
+	var reloads []*ReloadEvent
+	for event := range mgr.StreamUserTextInput(t.Context(), "hi") {
+		if event.Type == EventReloaded {
+			reloads = append(reloads, event.Reload)
+		}
+	}
+
+	if len(reloads) != 2 {
+		t.Fatalf("reload events = %+v, want mcp_servers.jsonl and prompts.jsonl", reloads)
+	}
+	for _, reload := range reloads {
+		if reload.Err != nil {
+			t.Errorf("reload of %s error = %v", reload.Path, reload.Err)
+		}
+		if strings.HasSuffix(reload.Path, "mcp_servers.jsonl") && !slices.Equal(reload.Servers, []string{"test"}) {
+			t.Errorf("reconnected servers = %v, want [test]", reload.Servers)
+		}
+	}
+	if len(stub.requests) != 1 || len(stub.requests[0].Tools) != 0 {
+		t.Errorf("requests = %d, want one request without the tools of the disabled server", len(stub.requests))
+	}
+	if len(mgr.MCPMgr.MCPServerList()) != 0 || mgr.PromptSvr().PromptByName("reloaded") == nil {
+		t.Errorf("files not reloaded, servers = %v", mgr.MCPMgr.MCPServerList())
+	}
+}
+
+func TestManager_ApplyConfig(t *testing.T) {
+	stub := newScriptedOpenAIStub(t)
+	mgr := newTestManager(t, ProviderOpenAI, stub.URL, ToolCallModeNative)
+
+	reloaded := *mgr.config
+	reloaded.root, reloaded.MaxTurns = nil, 5
+	if err := mgr.applyConfig(&reloaded); err != nil {
+		t.Fatalf("applyConfig() error = %v", err)
+	}
+	if mgr.config.MaxTurns != 5 || mgr.Model() != "test-model" {
+		t.Errorf("config = %d/%s, want the reloaded config", mgr.config.MaxTurns, mgr.Model())
+	}
+
+	// NOTE: The model set with /model survives the reload, until the profile is switched
+	mgr.SetModel("override-model")
+	if err := mgr.applyConfig(&reloaded); err != nil {
+		t.Fatalf("applyConfig() error = %v", err)
+	}
+	if mgr.Model() != "override-model" {
+		t.Errorf("Model() after reload = %s, want override-model", mgr.Model())
+	}
+
+	if err := mgr.SetProfile(""); err != nil {
+		t.Fatalf("SetProfile() error = %v", err)
+	}
+	if err := mgr.applyConfig(&reloaded); err != nil {
+		t.Fatalf("applyConfig() error = %v", err)
+	}
+	if mgr.Model() != "test-model" {
+		t.Errorf("Model() after switching profile = %s, want test-model", mgr.Model())
+	}
+}
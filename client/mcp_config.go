@@ -50,20 +50,23 @@ func NewMCPSvr(repo MCPSvrConfigRepo, logger log.Logger) *MCPConfigSvr {
 }
 
 func (svr *MCPConfigSvr) ensureDefaultConfig() {
-	if item := svr.MCPServerConfigByName("todo"); item != nil { // Not Find
-		defaultConfig := svr.DefaultConfig()
-		if err := svr.CreateMCPServerConfig(
-			defaultConfig.Name,
-			defaultConfig.Type,
-			defaultConfig.IsActive,
-
-			nil,
-			nil,
-			nil,
-			defaultConfig.Args,
-		); err != nil {
-			svr.Panic("failed to create default mcp server config: %v", err)
-		}
+	// NOTE: Only seed the default config into an empty registry, so that the user can remove or modify it
+	if len(svr.AllMCPServerConfig()) > 0 {
+		return
+	}
+
+	defaultConfig := svr.DefaultConfig()
+	if err := svr.CreateMCPServerConfig(
+		defaultConfig.Name,
+		defaultConfig.Type,
+		defaultConfig.IsActive,
+
+		nil,
+		nil,
+		&defaultConfig.Command,
+		defaultConfig.Args,
+	); err != nil {
+		svr.Panic("failed to create default mcp server config: %v", err)
 	}
 }
 
@@ -130,6 +133,7 @@ func (svr MCPConfigSvr) DeleteMCPServerConfigByName(name string) error {
 func (svr *MCPConfigSvr) DefaultConfig() *MCPSvrItem {
 	item := &MCPSvrItem{
 		Name:     DefaultMCPServerConfigName,
+		Type:     DefaultMCPServerConfigType,
 		IsActive: true,
 
 		Command: DefaultMCPServerConfigCommand,
@@ -2,12 +2,17 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os/exec"
+	"reflect"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/kydenul/log"
@@ -34,6 +39,7 @@ type MCPSvrManager struct {
 	mu       sync.RWMutex
 	sessions map[string]*mcp.ClientSession // Servername => Session 每个 session 连接到不同的 MCP Server
 	tools    map[string]string             // 工具名到服务器名的映射
+	items    map[string]MCPSvrItem         // Servername => 建立 session 时的配置，用于重新加载时比较
 }
 
 // NewMCPSvrManager returns a new instance of MCPSvrManager
@@ -49,6 +55,7 @@ func NewMCPSvrManager(repo MCPSvrConfigRepo, logger log.Logger) *MCPSvrManager {
 		}, nil),
 		sessions: make(map[string]*mcp.ClientSession),
 		tools:    make(map[string]string),
+		items:    make(map[string]MCPSvrItem),
 	}
 }
 
@@ -60,81 +67,278 @@ func (ss *MCPSvrManager) initMCPServer(ctx context.Context) {
 		return
 	}
 
+	// NOTE: 1. Create new session
+	dialed := make([]*dialedServer, 0, len(svrs))
+	for _, item := range svrs {
+		if !item.IsActive {
+			ss.Infof("MCP server %s is not active, skipping", item.Name)
+			continue
+		}
+
+		dialed = append(dialed, ss.dial(ctx, item))
+	}
+
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
-	// NOTE: 1. Clear exsist session and tools
-	for k := range ss.sessions {
-		_ = ss.sessions[k].Close()
+	// NOTE: 2. Clear exsist session and tools
+	for name := range ss.sessions {
+		ss.disconnect(name)
+	}
 
-		delete(ss.sessions, k)
+	// NOTE: 3. Store new session
+	for _, server := range dialed {
+		ss.register(server)
 	}
-	for k := range ss.tools {
-		delete(ss.tools, k)
+}
+
+// Reload reconnects the MCP servers added, removed or changed in the repository since they were connected,
+// and retries the servers that failed to connect. The sessions of the other servers are kept.
+// It returns the sorted names of the servers reconnected or disconnected.
+//
+// NOTE: The servers are connected without holding the lock and their sessions swapped in afterwards,
+// so a slow server does not block the tool calls to the other servers.
+func (ss *MCPSvrManager) Reload(ctx context.Context) []string {
+	active := make(map[string]*MCPSvrItem)
+	for _, item := range ss.repo.AllMCPServerConfigs() {
+		if item.IsActive {
+			active[item.Name] = item
+		}
 	}
 
-	// NOTE: 2. Create new session
-	var transport mcp.Transport
-	for _, item := range svrs {
-		if !item.IsActive {
-			ss.Infof("MCP server %s is not active, skipping", item.Name)
-			continue
+	// NOTE: 1. Compare with the servers connected
+	ss.mu.RLock()
+	stale, changed := make([]string, 0), make([]string, 0)
+	for name, connected := range ss.items {
+		if item, ok := active[name]; !ok || !reflect.DeepEqual(*item, connected) {
+			stale = append(stale, name)
+			changed = append(changed, name)
 		}
+	}
 
-		switch item.Type {
-		case ServerTypeStdio: // Stdio transport
-			ss.Info("Using stdio transport")
-			cmd := exec.Command(item.Command, item.Args...) //nolint:gosec
-			transport = &mcp.CommandTransport{Command: cmd}
-
-		case ServerTypeSSE: // HTTP transport
-			ss.Info("Using SSE transport")
-			httpClient := &http.Client{} // 简化版，可扩展以添加头部
-
-			transport = &mcp.SSEClientTransport{
-				Endpoint:   item.BaseURL,
-				HTTPClient: httpClient,
-			}
-
-		case ServerTypeStreamableHTTP: // HTTP transport
-			ss.Info("Using Streamable HTTP transport")
-			httpClient := &http.Client{} // 简化版，可扩展以添加头部
-
-			transport = &mcp.StreamableClientTransport{
-				Endpoint:   item.BaseURL,
-				HTTPClient: httpClient,
-				MaxRetries: 1,
-			}
-
-		default:
-			ss.Warnf("Skipping server '%s': no command or httpUrl configured", item.Name)
+	toDial := make([]*MCPSvrItem, 0)
+	for name, item := range active {
+		if _, ok := ss.items[name]; ok && !slices.Contains(stale, name) {
 			continue
 		}
 
-		// NOTE: 3.1 Create MCP Server Session
-		ss.Infof("Connecting to server '%s'...", item.Name)
-		session, err := ss.client.Connect(ctx, transport, nil)
-		if err != nil {
-			ss.Infof("Failed to connect to server '%s': %v", item.Name, err)
-			continue
+		if !slices.Contains(changed, name) {
+			changed = append(changed, name)
 		}
-		ss.sessions[item.Name] = session
-		ss.Infof("Successfully connected to server '%s'", item.Name)
+		toDial = append(toDial, item)
+	}
+	ss.mu.RUnlock()
 
-		// NOTE: 3.2 Register tools
-		tools, err := session.ListTools(ctx, &mcp.ListToolsParams{})
-		if err != nil {
-			ss.Errorf("Failed to list tools for server '%s': %v", item.Name, err)
-			continue
+	// NOTE: 2. Connect without the lock, the tools of the other servers stay callable meanwhile
+	dialed := make([]*dialedServer, 0, len(toDial))
+	for _, item := range toDial {
+		dialed = append(dialed, ss.dial(ctx, item))
+	}
+
+	// NOTE: 3. Swap the sessions
+	ss.mu.Lock()
+	for _, name := range stale {
+		ss.Infof("MCP server '%s' is removed or changed, disconnecting", name)
+		ss.disconnect(name)
+	}
+	for _, server := range dialed {
+		ss.register(server)
+	}
+	ss.mu.Unlock()
+
+	sort.Strings(changed)
+
+	return changed
+}
+
+// dialedServer is the session of a server connected by dial, nil if the connection failed
+type dialedServer struct {
+	item    *MCPSvrItem
+	session *mcp.ClientSession
+	tools   []string
+}
+
+// dial creates the session of the server and lists its tools, ss.mu must not be held
+func (ss *MCPSvrManager) dial(ctx context.Context, item *MCPSvrItem) *dialedServer {
+	server := &dialedServer{item: item}
+
+	transport, err := ss.newTransport(item)
+	if err != nil {
+		ss.Warnf("Skipping server '%s': %v", item.Name, err)
+		return server
+	}
+
+	// NOTE: 1. Create MCP Server Session
+	ss.Infof("Connecting to server '%s'...", item.Name)
+	session, err := ss.client.Connect(ctx, transport, nil)
+	if err != nil {
+		ss.Infof("Failed to connect to server '%s': %v", item.Name, err)
+		return server
+	}
+	server.session = session
+	ss.Infof("Successfully connected to server '%s'", item.Name)
+
+	// NOTE: 2. List tools
+	tools, err := session.ListTools(ctx, &mcp.ListToolsParams{})
+	if err != nil {
+		ss.Errorf("Failed to list tools for server '%s': %v", item.Name, err)
+		return server
+	}
+
+	for _, tool := range tools.Tools {
+		server.tools = append(server.tools, tool.Name)
+	}
+
+	return server
+}
+
+// register stores the session of the server and registers its tools, ss.mu must be held
+func (ss *MCPSvrManager) register(server *dialedServer) {
+	if server.session == nil {
+		return
+	}
+
+	name := server.item.Name
+	if _, ok := ss.sessions[name]; ok {
+		ss.disconnect(name)
+	}
+	ss.sessions[name] = server.session
+	ss.items[name] = *server.item
+
+	for _, tool := range server.tools {
+		ss.tools[tool] = name
+		ss.Infof("Registered tool '%s' for server '%s'", tool, name)
+	}
+}
+
+// disconnect closes the session of the server and unregisters its tools, ss.mu must be held
+func (ss *MCPSvrManager) disconnect(name string) {
+	if session, ok := ss.sessions[name]; ok {
+		_ = session.Close()
+	}
+	delete(ss.sessions, name)
+	delete(ss.items, name)
+
+	for tool, svrName := range ss.tools {
+		if svrName == name {
+			delete(ss.tools, tool)
+		}
+	}
+}
+
+// newTransport creates the MCP transport described by the server config
+func (ss *MCPSvrManager) newTransport(item *MCPSvrItem) (mcp.Transport, error) {
+	switch item.Type {
+	case ServerTypeStdio: // Stdio transport
+		ss.Info("Using stdio transport")
+		if item.Command == "" {
+			return nil, errors.New("no command configured")
 		}
 
-		for _, tool := range tools.Tools {
-			ss.tools[tool.Name] = item.Name
-			ss.Infof("Registered tool '%s' for server '%s'", tool.Name, item.Name)
+		cmd := exec.Command(item.Command, item.Args...) //nolint:gosec
+		return &mcp.CommandTransport{Command: cmd}, nil
+
+	case ServerTypeSSE: // HTTP transport
+		ss.Info("Using SSE transport")
+		if item.BaseURL == "" {
+			return nil, errors.New("no baseUrl configured")
+		}
+
+		httpClient := &http.Client{} // 简化版，可扩展以添加头部
+		return &mcp.SSEClientTransport{
+			Endpoint:   item.BaseURL,
+			HTTPClient: httpClient,
+		}, nil
+
+	case ServerTypeStreamableHTTP: // HTTP transport
+		ss.Info("Using Streamable HTTP transport")
+		if item.BaseURL == "" {
+			return nil, errors.New("no baseUrl configured")
 		}
+
+		httpClient := &http.Client{} // 简化版，可扩展以添加头部
+		return &mcp.StreamableClientTransport{
+			Endpoint:   item.BaseURL,
+			HTTPClient: httpClient,
+			MaxRetries: 1,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown server type '%s'", item.Type)
 	}
 }
 
+// MCPSvrProbe is the result of probing an MCP server with MCPSvrManager.Probe
+type MCPSvrProbe struct {
+	Name    string
+	Server  *mcp.Implementation // Server name and version reported during initialization
+	Connect time.Duration       // Time to start the transport and initialize the session
+
+	Tools        []*mcp.Tool
+	ToolsLatency time.Duration
+	ToolsErr     error
+
+	Resources        []*mcp.Resource
+	ResourcesLatency time.Duration
+	ResourcesErr     error
+
+	ResourceTemplates        []*mcp.ResourceTemplate
+	ResourceTemplatesLatency time.Duration
+	ResourceTemplatesErr     error
+}
+
+// Probe connects to the MCP server described by item in a new session, lists its tools,
+// resources and resource templates, and measures the latency of each step.
+// The session is closed before returning and the connected sessions are left untouched.
+func (ss *MCPSvrManager) Probe(ctx context.Context, item *MCPSvrItem) (*MCPSvrProbe, error) {
+	transport, err := ss.newTransport(item)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of server '%s': %w", item.Name, err)
+	}
+
+	probe := &MCPSvrProbe{Name: item.Name}
+
+	start := time.Now()
+	session, err := ss.client.Connect(ctx, transport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server '%s': %w", item.Name, err)
+	}
+	defer session.Close()
+	probe.Connect = time.Since(start)
+
+	if result := session.InitializeResult(); result != nil {
+		probe.Server = result.ServerInfo
+	}
+
+	start = time.Now()
+	if tools, err := session.ListTools(ctx, &mcp.ListToolsParams{}); err != nil {
+		probe.ToolsErr = err
+	} else {
+		probe.Tools = tools.Tools
+	}
+	probe.ToolsLatency = time.Since(start)
+
+	start = time.Now()
+	if resources, err := session.ListResources(ctx, &mcp.ListResourcesParams{}); err != nil {
+		probe.ResourcesErr = err
+	} else {
+		probe.Resources = resources.Resources
+	}
+	probe.ResourcesLatency = time.Since(start)
+
+	start = time.Now()
+	if templates, err := session.ListResourceTemplates(ctx, &mcp.ListResourceTemplatesParams{}); err != nil {
+		probe.ResourceTemplatesErr = err
+	} else {
+		probe.ResourceTemplates = templates.ResourceTemplates
+	}
+	probe.ResourceTemplatesLatency = time.Since(start)
+
+	ss.Infof("Probed server '%s': %+v", item.Name, probe)
+
+	return probe, nil
+}
+
 // ClossAllSession closes all sessions and clears the session
 func (ss *MCPSvrManager) ClossAllSession() {
 	ss.mu.Lock()
@@ -150,6 +354,7 @@ func (ss *MCPSvrManager) ClossAllSession() {
 
 		ss.Infof("  --> Close session for server '%s'", name)
 		delete(ss.sessions, name)
+		delete(ss.items, name)
 	}
 	ss.Infof("All sessions are closed.")
 }
@@ -178,6 +383,30 @@ func (ss *MCPSvrManager) CallTool(
 	})
 }
 
+// Tools returns the tools of all connected servers
+func (ss *MCPSvrManager) Tools(ctx context.Context) []*mcp.Tool {
+	vTool := make([]*mcp.Tool, 0)
+	for _, svrName := range ss.MCPServerList() {
+		tools, err := ss.ToolsByServerName(ctx, svrName)
+		if err != nil {
+			ss.Warnf("Failed to get tools for server '%s': %v", svrName, err)
+			continue
+		}
+
+		vTool = append(vTool, tools...)
+	}
+
+	return vTool
+}
+
+// ToolServerName returns the name of the server the tool is routed to, or empty if not found
+func (ss *MCPSvrManager) ToolServerName(toolName string) string {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	return ss.tools[toolName]
+}
+
 func (ss *MCPSvrManager) ExtractMCPToolUse(content string) *MCPToolUse {
 	match := regexp.MustCompile("(?s)<use_mcp_tool>(.*?)</use_mcp_tool>").
 		FindStringSubmatch(content)
@@ -225,16 +454,15 @@ func (ss *MCPSvrManager) ExtractMCPToolUse(content string) *MCPToolUse {
 		Arguments:  arguments,
 	}
 
-	ss.Infof("Extracted MCP Tool Use: %+v", temp)
+	ss.Infof("Extracted MCP Tool Use: %s/%s", temp.ServerName, temp.ToolsName)
 
 	return temp
 }
 
 // MCPServerList returns the list of connected MCP servers
 func (ss *MCPSvrManager) MCPServerList() []string {
-	vSvr := make([]string, 0, len(ss.sessions))
-
 	ss.mu.RLock()
+	vSvr := make([]string, 0, len(ss.sessions))
 	for name := range ss.sessions {
 		vSvr = append(vSvr, name)
 	}
@@ -250,7 +478,7 @@ func (ss *MCPSvrManager) ToolsByServerName(
 	ctx context.Context,
 	serverName string,
 ) ([]*mcp.Tool, error) {
-	vTool := make([]*mcp.Tool, 0, len(ss.sessions))
+	vTool := make([]*mcp.Tool, 0)
 
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
@@ -295,7 +523,7 @@ func (ss *MCPSvrManager) ResourceTemplatesByServerName(
 		return nil, err
 	}
 
-	vResourceTemplate := make([]*mcp.ResourceTemplate, 0, len(ss.sessions))
+	vResourceTemplate := make([]*mcp.ResourceTemplate, 0)
 	vResourceTemplate = append(vResourceTemplate, templates.ResourceTemplates...)
 
 	ss.Infof("Found %d resource templates for server '%s'",
@@ -324,7 +552,7 @@ func (ss *MCPSvrManager) ResourcesByServerName(
 		return nil, err
 	}
 
-	vResource := make([]*mcp.Resource, 0, len(ss.sessions))
+	vResource := make([]*mcp.Resource, 0)
 	vResource = append(vResource, resources.Resources...)
 
 	ss.Infof("Found %d resources for server '%s'", len(resources.Resources), serverName)
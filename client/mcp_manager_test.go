@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type echoInput struct {
+	Text string `json:"text"`
+}
+
+// newTestMCPServer returns a Streamable HTTP MCP server exposing one resource and two tools,
+// echo and wait, which blocks until the call is canceled
+func newTestMCPServer(t *testing.T) *httptest.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test-server", Version: "v0.0.1"}, nil)
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "echo",
+		Description: "Echo the text",
+	}, func(_ context.Context, _ *mcp.CallToolRequest, in echoInput) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "echo: " + in.Text}}}, nil, nil
+	})
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "wait",
+		Description: "Wait until canceled",
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "done"}}}, nil, nil
+		}
+	})
+	server.AddResource(&mcp.Resource{
+		Name: "readme",
+		URI:  "file:///README.md",
+	}, func(context.Context, *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		return &mcp.ReadResourceResult{}, nil
+	})
+
+	httpServer := httptest.NewServer(mcp.NewStreamableHTTPHandler(
+		func(*http.Request) *mcp.Server { return server }, nil))
+	t.Cleanup(httpServer.Close)
+
+	return httpServer
+}
+
+func TestMCPSvrManager_Probe(t *testing.T) {
+	httpServer := newTestMCPServer(t)
+	mgr := NewMCPSvrManager(nil, &discardLogger{})
+
+	probe, err := mgr.Probe(context.Background(), &MCPSvrItem{
+		Name:    "test",
+		Type:    ServerTypeStreamableHTTP,
+		BaseURL: httpServer.URL,
+	})
+	if err != nil {
+		t.Fatalf("Probe() error = %v", err)
+	}
+
+	if probe.Server == nil || probe.Server.Name != "test-server" {
+		t.Errorf("Probe().Server = %+v, want test-server", probe.Server)
+	}
+	if probe.ToolsErr != nil || len(probe.Tools) != 2 || probe.Tools[0].Name != "echo" || probe.Tools[1].Name != "wait" {
+		t.Errorf("Probe().Tools = %v, err = %v, want [echo wait]", probe.Tools, probe.ToolsErr)
+	}
+	if probe.ResourcesErr != nil || len(probe.Resources) != 1 || probe.Resources[0].URI != "file:///README.md" {
+		t.Errorf("Probe().Resources = %v, err = %v, want [file:///README.md]", probe.Resources, probe.ResourcesErr)
+	}
+	if probe.Connect <= 0 {
+		t.Errorf("Probe().Connect = %v, want > 0", probe.Connect)
+	}
+	if len(mgr.sessions) != 0 {
+		t.Errorf("Probe() should not register the session, got %d sessions", len(mgr.sessions))
+	}
+}
+
+func TestMCPSvrManager_ProbeInvalidConfig(t *testing.T) {
+	mgr := NewMCPSvrManager(nil, &discardLogger{})
+
+	tests := []*MCPSvrItem{
+		{Name: "no-command", Type: ServerTypeStdio},
+		{Name: "no-url", Type: ServerTypeStreamableHTTP},
+		{Name: "unknown", Type: "websocket", BaseURL: "ws://localhost"},
+	}
+
+	for _, item := range tests {
+		t.Run(item.Name, func(t *testing.T) {
+			if _, err := mgr.Probe(context.Background(), item); err == nil {
+				t.Errorf("Probe() should fail for %+v", item)
+			}
+		})
+	}
+}
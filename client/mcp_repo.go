@@ -1,19 +1,18 @@
 package client
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
-	"os"
 	"sort"
-	"strings"
 	"sync"
 
-	"github.com/bytedance/sonic"
 	"github.com/kydenul/log"
 )
 
-var _ MCPSvrConfigRepo = (*MCPSvrConfigFileRepo)(nil)
+var (
+	_ MCPSvrConfigRepo = (*MCPSvrConfigFileRepo)(nil)
+	_ ReloadableRepo   = (*MCPSvrConfigFileRepo)(nil)
+)
 
 // FileRepo implements ChatRepository using file storage with async operations
 type MCPSvrConfigFileRepo struct {
@@ -56,7 +55,7 @@ func (r *MCPSvrConfigFileRepo) loadCacheSync() error {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	configs, err := loadMCPServerConfigsFromJSONL(r.dataFile)
+	configs, err := loadMCPServerConfigsFromJSONL(r.dataFile, r.Logger)
 	if err != nil {
 		r.Errorf("failed to load initial data: %v", err)
 		return fmt.Errorf("failed to load initial data: %w", err)
@@ -73,6 +72,33 @@ func (r *MCPSvrConfigFileRepo) loadCacheSync() error {
 	return nil
 }
 
+// Path returns the JSONL file of the repository
+func (r *MCPSvrConfigFileRepo) Path() string { return r.dataFile }
+
+// Reload replaces the cache with the content of the file
+func (r *MCPSvrConfigFileRepo) Reload() error {
+	r.mu.RLock()
+	configs, err := loadMCPServerConfigsFromJSONL(r.dataFile, r.Logger)
+	r.mu.RUnlock()
+	if err != nil {
+		r.Errorf("failed to reload data: %v", err)
+		return fmt.Errorf("failed to reload data: %w", err)
+	}
+
+	cache := make(map[string]*MCPSvrItem, len(configs))
+	for _, config := range configs {
+		cache[config.Name] = config
+	}
+
+	r.cacheMu.Lock()
+	r.cache = cache
+	r.cacheMu.Unlock()
+
+	r.Infof("Reloaded %d MCP Servers from %s", len(cache), r.dataFile)
+
+	return nil
+}
+
 func (r *MCPSvrConfigFileRepo) persistCache() error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -121,9 +147,8 @@ func (r *MCPSvrConfigFileRepo) MCPServerConfigByName(name string) (*MCPSvrItem,
 }
 
 func (r *MCPSvrConfigFileRepo) AllMCPServerConfigs() []*MCPSvrItem {
-	items := make([]*MCPSvrItem, 0, len(r.cache))
-
 	r.cacheMu.RLock()
+	items := make([]*MCPSvrItem, 0, len(r.cache))
 	for _, val := range r.cache {
 		items = append(items, val)
 	}
@@ -204,51 +229,19 @@ func (r *MCPSvrConfigFileRepo) DeleteMCPServerConfigByName(name string) error {
 	return nil
 }
 
-// loadMCPServerConfigsFromJSONL loads MCP configs from the JSONL file
-func loadMCPServerConfigsFromJSONL(jsonl string) ([]*MCPSvrItem, error) {
-	file, err := os.Open(jsonl) //nolint:gosec
+// loadMCPServerConfigsFromJSONL loads MCP configs from the JSONL file,
+// recovering it from the backup if it fails to parse
+func loadMCPServerConfigsFromJSONL(jsonl string, logger log.Logger) ([]*MCPSvrItem, error) {
+	configs, err := loadJSONL[MCPSvrItem](jsonl, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, fmt.Errorf("failed to load file: %w", err)
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	items := make([]*MCPSvrItem, 0, 128)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		item := &MCPSvrItem{}
-		if err := sonic.UnmarshalString(line, item); err != nil {
-			continue // skip invalid lines
-		}
 
-		items = append(items, item)
-	}
-
-	return items, scanner.Err()
+	return configs, nil
 }
 
-// persistMCPServerConfigToJSONL writes MCP configs to the JSONL file
+// persistMCPServerConfigToJSONL writes MCP configs to the JSONL file atomically,
+// keeping the previous version as backup
 func persistMCPServerConfigToJSONL(jsonl string, configs []*MCPSvrItem) error {
-	file, err := os.Create(jsonl) //nolint:gosec
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer file.Close()
-
-	for _, config := range configs {
-		data, err := sonic.Marshal(config)
-		if err != nil {
-			return err
-		}
-
-		if _, err := file.Write(append(data, '\n')); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return writeJSONL(jsonl, configs)
 }
@@ -5,10 +5,13 @@ import (
 	"time"
 
 	"github.com/bytedance/sonic"
+	"github.com/spf13/cast"
 )
 
 const (
 	DefaultContentType = "text"
+
+	ToolCallTypeFunction = "function"
 )
 
 type ContentPart struct {
@@ -25,23 +28,43 @@ type Message struct {
 	Timestamp     *time.Time `json:"timestamp,omitempty"`
 	UnixTimestamp int64      `json:"unix_timestamp,omitempty"`
 
-	ReasoningContent string         `json:"reasoning_content,omitempty"`
-	ReasoningEffort  string         `json:"reasoning_effort,omitempty"`
-	Links            []string       `json:"links,omitempty"`
-	Images           []string       `json:"images,omitempty"`
-	Model            string         `json:"model,omitempty"`
-	Provider         string         `json:"provider,omitempty"`
-	ID               string         `json:"id,omitempty"`
-	ParentID         string         `json:"parent_id,omitempty"`
-	Server           string         `json:"server,omitempty"`
-	Tool             string         `json:"tool,omitempty"`
-	Arguments        map[string]any `json:"arguments,omitempty"`
+	ReasoningContent   string         `json:"reasoning_content,omitempty"`
+	ReasoningSignature string         `json:"reasoning_signature,omitempty"` // Anthropic extended thinking
+	ReasoningEffort    string         `json:"reasoning_effort,omitempty"`
+	Links              []string       `json:"links,omitempty"`
+	Images             []string       `json:"images,omitempty"`
+	Model              string         `json:"model,omitempty"`
+	Provider           string         `json:"provider,omitempty"`
+	ID                 string         `json:"id,omitempty"`
+	ParentID           string         `json:"parent_id,omitempty"`
+	Server             string         `json:"server,omitempty"`
+	Tool               string         `json:"tool,omitempty"`
+	Arguments          map[string]any `json:"arguments,omitempty"`
+
+	ToolCalls  []*ToolCall `json:"tool_calls,omitempty"`   // Native tool calls requested by the assistant
+	ToolCallID string      `json:"tool_call_id,omitempty"` // The native tool call answered by the tool message
+
+	Usage *Usage `json:"usage,omitempty"` // Token usage of the assistant message
+}
+
+// ToolCall is a native tool call requested by the assistant
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"` // Always "function"
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction is the function called by a ToolCall
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON encoded arguments
 }
 
 // MessageOption contains optional fields for creating a message
 type MessageOption struct {
-	ReasoningContent string
-	ReasoningEffort  string
+	ReasoningContent   string
+	ReasoningSignature string
+	ReasoningEffort    string
 
 	Links  []string
 	Images []string
@@ -53,6 +76,10 @@ type MessageOption struct {
 	Server    string
 	Tool      string
 	Arguments map[string]any
+
+	ToolCallID string
+
+	Usage *Usage
 }
 
 func NewMessage(role, content string, timestamp time.Time, unixTimestamp int64) *Message {
@@ -73,6 +100,9 @@ func NewMessageWithOption(role, content string, opt *MessageOption) *Message {
 		if opt.ReasoningContent != "" {
 			message.ReasoningContent = opt.ReasoningContent
 		}
+		if opt.ReasoningSignature != "" {
+			message.ReasoningSignature = opt.ReasoningSignature
+		}
 		if opt.ReasoningEffort != "" {
 			message.ReasoningEffort = opt.ReasoningEffort
 		}
@@ -106,11 +136,27 @@ func NewMessageWithOption(role, content string, opt *MessageOption) *Message {
 		if opt.Arguments != nil {
 			message.Arguments = opt.Arguments
 		}
+		if opt.ToolCallID != "" {
+			message.ToolCallID = opt.ToolCallID
+		}
+		if opt.Usage != nil {
+			message.Usage = opt.Usage
+		}
 	}
 
 	return message
 }
 
+// Reasoning returns the reasoning of the message, which is never sent back to the provider as the answer.
+// NOTE: The chats saved by the previous versions duplicate the answer as the reasoning.
+func (m *Message) Reasoning() string {
+	if m.ReasoningContent == cast.ToString(m.Content) {
+		return ""
+	}
+
+	return m.ReasoningContent
+}
+
 // LoadMessageFromString loads a message from a JSON string
 func LoadMessageFromJSON(str string) (*Message, error) {
 	if str == "" {
@@ -50,6 +50,12 @@ func (svr *PromptSvr) DeletePrompt(name string) error {
 	return svr.repo.DeletePromptByName(name)
 }
 
+// RenamePrompt replaces the prompt name with prompt, saved under the new name of prompt.
+// It fails if a prompt with the new name already exists.
+func (svr *PromptSvr) RenamePrompt(name string, prompt *PromptItem) error {
+	return svr.repo.RenamePromptByName(name, prompt)
+}
+
 // ListPrompts returns all prompt configurations
 func (svr *PromptSvr) AllPrompts() []*PromptItem {
 	return svr.repo.AllPrompts()
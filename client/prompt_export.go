@@ -0,0 +1,75 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// promptFrontMatterDelimiter delimits the front matter of a prompt in Markdown format
+const promptFrontMatterDelimiter = "---"
+
+// PromptToMarkdown renders the prompt as Markdown, with its name and description in a front matter:
+//
+//	---
+//	name: mcp
+//	description: mcp prompt
+//	---
+//	<content>
+func PromptToMarkdown(prompt *PromptItem) string {
+	var builder strings.Builder
+
+	builder.WriteString(promptFrontMatterDelimiter + "\n")
+	fmt.Fprintf(&builder, "name: %s\n", prompt.Name)
+	if prompt.Description != "" {
+		fmt.Fprintf(&builder, "description: %s\n", prompt.Description)
+	}
+	builder.WriteString(promptFrontMatterDelimiter + "\n")
+	builder.WriteString(strings.TrimRight(prompt.Content, "\n") + "\n")
+
+	return builder.String()
+}
+
+// ParsePromptMarkdown parses a prompt rendered by PromptToMarkdown.
+// The front matter is optional, without it the whole document is the content and the name is empty.
+func ParsePromptMarkdown(markdown string) (*PromptItem, error) {
+	prompt := &PromptItem{}
+
+	markdown = strings.ReplaceAll(markdown, "\r\n", "\n")
+	if strings.HasPrefix(markdown, promptFrontMatterDelimiter+"\n") {
+		header, content, ok := strings.Cut(
+			strings.TrimPrefix(markdown, promptFrontMatterDelimiter+"\n"), "\n"+promptFrontMatterDelimiter+"\n")
+		if !ok {
+			return nil, errors.New("front matter is not closed by " + promptFrontMatterDelimiter)
+		}
+
+		for lineNum, line := range strings.Split(header, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid front matter line %d: %s", lineNum+2, line)
+			}
+
+			switch strings.TrimSpace(key) {
+			case "name":
+				prompt.Name = strings.TrimSpace(value)
+			case "description":
+				prompt.Description = strings.TrimSpace(value)
+			default:
+				return nil, fmt.Errorf("unknown front matter key '%s' in line %d", strings.TrimSpace(key), lineNum+2)
+			}
+		}
+
+		markdown = content
+	}
+
+	prompt.Content = strings.TrimSpace(markdown)
+	if prompt.Content == "" {
+		return nil, errors.New("prompt content is empty")
+	}
+
+	return prompt, nil
+}
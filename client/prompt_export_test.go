@@ -0,0 +1,68 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestPromptMarkdown_RoundTrip(t *testing.T) {
+	prompt := &PromptItem{
+		Name:        "review",
+		Description: "code review: be strict",
+		Content:     "You are a reviewer.\n\n---\n\nReview the diff.",
+	}
+
+	got, err := ParsePromptMarkdown(PromptToMarkdown(prompt))
+	if err != nil {
+		t.Fatalf("ParsePromptMarkdown() error = %v", err)
+	}
+	if *got != *prompt {
+		t.Errorf("ParsePromptMarkdown() = %+v, want %+v", got, prompt)
+	}
+}
+
+func TestParsePromptMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		expected PromptItem
+		wantErr  bool
+	}{
+		{
+			name:     "without front matter",
+			markdown: "\n# Title\n\nBody\n",
+			expected: PromptItem{Content: "# Title\n\nBody"},
+		},
+		{
+			name:     "name only",
+			markdown: "---\r\nname: foo\r\n---\r\nBody\r\n",
+			expected: PromptItem{Name: "foo", Content: "Body"},
+		},
+		{
+			name:     "unclosed front matter",
+			markdown: "---\nname: foo\nBody\n",
+			wantErr:  true,
+		},
+		{
+			name:     "unknown key",
+			markdown: "---\nauthor: foo\n---\nBody\n",
+			wantErr:  true,
+		},
+		{
+			name:     "empty content",
+			markdown: "---\nname: foo\n---\n\n",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePromptMarkdown(tt.markdown)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePromptMarkdown() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && *got != tt.expected {
+				t.Errorf("ParsePromptMarkdown() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
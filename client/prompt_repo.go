@@ -1,19 +1,18 @@
 package client
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
-	"os"
 	"sort"
-	"strings"
 	"sync"
 
-	"github.com/bytedance/sonic"
 	"github.com/kydenul/log"
 )
 
-var _ PromptRepo = (*PromptFileRepo)(nil)
+var (
+	_ PromptRepo     = (*PromptFileRepo)(nil)
+	_ ReloadableRepo = (*PromptFileRepo)(nil)
+)
 
 type PromptFileRepo struct {
 	log.Logger
@@ -57,7 +56,7 @@ func (r *PromptFileRepo) loadCacheSync() error {
 	r.mtx.RLock()
 	defer r.mtx.RUnlock()
 
-	prompts, err := loadPromptFromJSONL(r.dataFile)
+	prompts, err := loadPromptFromJSONL(r.dataFile, r.Logger)
 	if err != nil {
 		r.Errorf("failed to load initial data: %v", err)
 		return fmt.Errorf("failed to load initial data: %w", err)
@@ -74,6 +73,33 @@ func (r *PromptFileRepo) loadCacheSync() error {
 	return nil
 }
 
+// Path returns the JSONL file of the repository
+func (r *PromptFileRepo) Path() string { return r.dataFile }
+
+// Reload replaces the cache with the content of the file
+func (r *PromptFileRepo) Reload() error {
+	r.mtx.RLock()
+	prompts, err := loadPromptFromJSONL(r.dataFile, r.Logger)
+	r.mtx.RUnlock()
+	if err != nil {
+		r.Errorf("failed to reload data: %v", err)
+		return fmt.Errorf("failed to reload data: %w", err)
+	}
+
+	cache := make(map[string]*PromptItem, len(prompts))
+	for _, prompt := range prompts {
+		cache[prompt.Name] = prompt
+	}
+
+	r.cacheMtx.Lock()
+	r.cache = cache
+	r.cacheMtx.Unlock()
+
+	r.Infof("Reloaded %d prompts from %s", len(cache), r.dataFile)
+
+	return nil
+}
+
 func (r *PromptFileRepo) persistCacheSync() error {
 	r.mtx.RLock()
 	defer r.mtx.RUnlock()
@@ -120,9 +146,8 @@ func (r *PromptFileRepo) PromptByName(name string) (*PromptItem, error) {
 }
 
 func (r *PromptFileRepo) AllPrompts() []*PromptItem {
-	items := make([]*PromptItem, 0, len(r.cache))
-
 	r.cacheMtx.RLock()
+	items := make([]*PromptItem, 0, len(r.cache))
 	for _, item := range r.cache {
 		items = append(items, item)
 	}
@@ -201,51 +226,57 @@ func (r *PromptFileRepo) DeletePromptByName(name string) error {
 	return nil
 }
 
-// loadPromptFromJSONL loads prompts from the JSONL file
-func loadPromptFromJSONL(jsonl string) ([]*PromptItem, error) {
-	file, err := os.Open(jsonl) //nolint:gosec
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+// RenamePromptByName replaces the prompt name with item, saved under the name of item in a single write
+func (r *PromptFileRepo) RenamePromptByName(name string, item *PromptItem) error {
+	if name == "" || item == nil || item.Name == "" {
+		r.Errorf("name or item is empty")
+		return errors.New("name or item is empty")
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	items := make([]*PromptItem, 0, 128)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
+	// NOTE: rename prompt in cache
+	r.cacheMtx.Lock()
+	oldCache, ok := r.cache[name]
+	if !ok {
+		r.cacheMtx.Unlock()
+		return fmt.Errorf("prompt [%s] not found", name)
+	}
+	if _, exists := r.cache[item.Name]; exists && item.Name != name {
+		r.cacheMtx.Unlock()
+		return fmt.Errorf("prompt [%s] already exists", item.Name)
+	}
 
-		item := &PromptItem{}
-		if err := sonic.UnmarshalString(line, item); err != nil {
-			continue // skip invalid lines
-		}
+	delete(r.cache, name)
+	r.cache[item.Name] = item
+	r.cacheMtx.Unlock()
 
-		items = append(items, item)
+	// NOTE: persist cache
+	if err := r.persistCacheSync(); err != nil {
+		r.Errorf("failed to persist cache: %v => rollback", err)
+
+		// Rollback cache change
+		r.cacheMtx.Lock()
+		delete(r.cache, item.Name)
+		r.cache[name] = oldCache
+		r.cacheMtx.Unlock()
+		return fmt.Errorf("failed to persist cache: %w", err)
 	}
 
-	return items, nil
+	r.Infof("Rename prompt in cache and persisted: %s => %s", name, item.Name)
+
+	return nil
 }
 
-// persistPromptToJSONL writes prompts to the JSONL file
-func persistPromptToJSONL(jsonl string, prompts []*PromptItem) error {
-	file, err := os.Create(jsonl) //nolint:gosec
+// loadPromptFromJSONL loads prompts from the JSONL file, recovering it from the backup if it fails to parse
+func loadPromptFromJSONL(jsonl string, logger log.Logger) ([]*PromptItem, error) {
+	prompts, err := loadJSONL[PromptItem](jsonl, logger)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return nil, fmt.Errorf("failed to load file: %w", err)
 	}
-	defer file.Close()
 
-	for _, prompt := range prompts {
-		data, err := sonic.Marshal(prompt)
-		if err != nil {
-			return err
-		}
-
-		if _, err := file.Write(append(data, '\n')); err != nil {
-			return err
-		}
-	}
+	return prompts, nil
+}
 
-	return nil
+// persistPromptToJSONL writes prompts to the JSONL file atomically, keeping the previous version as backup
+func persistPromptToJSONL(jsonl string, prompts []*PromptItem) error {
+	return writeJSONL(jsonl, prompts)
 }
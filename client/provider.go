@@ -3,8 +3,11 @@ package client
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -17,17 +20,50 @@ const (
 	ModelDeepSeekR1  = "deepseek-r1"
 	ModelDeepSeekV31 = "DeepSeek-V3_1"
 
-	DefaultTimeout         = 60 * time.Second
-	DefaultStreamChunkSize = 16 // default stream chunk size
+	DefaultTimeout         = 60 * time.Second // 等待响应头的超时时间，流式响应的持续时间由 ctx 控制
+	DefaultDialTimeout     = 30 * time.Second // 建立连接的超时时间
+	DefaultStreamChunkSize = 16               // default stream chunk size
 )
 
+// providerTransport is shared by the providers, so that the connections are reused
+var providerTransport = newProviderTransport()
+
+// NewHTTPClient returns the HTTP client of the providers. Only connecting and waiting for the response
+// headers time out, http.Client.Timeout would cut off any stream longer than it, the stream is bounded
+// by the context of the request instead.
+func NewHTTPClient() *http.Client {
+	return &http.Client{Transport: providerTransport}
+}
+
+func newProviderTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: DefaultDialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.ResponseHeaderTimeout = DefaultTimeout
+
+	return transport
+}
+
+// ErrEmptyResponse is returned when the provider finishes the stream without any content
+var ErrEmptyResponse = errors.New("empty response from provider")
+
 type BaseProvider struct {
 	log.Logger
 
 	Client *http.Client
+	Retry  RetryPolicy // 请求失败时的重试策略
+
+	Redactor  *Redactor // 记录请求日志前屏蔽密钥，nil 时只屏蔽认证头
+	DebugHTTP bool      // 是否在日志中记录请求体与响应 (对话内容)
+
+	// ProcessResponse parses the response body into stream chunks,
+	// ProcessStreamableResponse (OpenAI compatible SSE) is used if nil
+	ProcessResponse func(ctx context.Context, resp *http.Response, respChan chan StreamChunk)
 }
 
+// DoCallStreamableChatCompletions sends the request in a goroutine and streams the chunks of the response,
+// the request is aborted when ctx is done
 func (p *BaseProvider) DoCallStreamableChatCompletions(
+	ctx context.Context,
 	messages []*Message, systemPrompt *string,
 	BuildRequest func(
 		context.Context,
@@ -37,9 +73,6 @@ func (p *BaseProvider) DoCallStreamableChatCompletions(
 	) (*http.Request, error),
 ) <-chan StreamChunk {
 	respChan := make(chan StreamChunk, DefaultStreamChunkSize)
-	// ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
-	// defer cancel()
-	ctx := context.Background()
 
 	// NOTE: 异步调用
 	go func() {
@@ -53,7 +86,7 @@ func (p *BaseProvider) DoCallStreamableChatCompletions(
 		}
 
 		// Make request
-		resp, err := p.Client.Do(req)
+		resp, err := p.doWithRetry(ctx, req)
 		if err != nil {
 			p.Errorf("HTTP request error: %v", err)
 			respChan <- StreamChunk{Error: fmt.Errorf("HTTP error getting chat response: %w", err)}
@@ -61,21 +94,54 @@ func (p *BaseProvider) DoCallStreamableChatCompletions(
 		}
 		defer resp.Body.Close()
 
-		p.Infof("Response status: %d", resp.StatusCode)
-		if resp.StatusCode != http.StatusOK {
-			p.Errorf("HTTP error: status code %d", resp.StatusCode)
-			respChan <- StreamChunk{Error: fmt.Errorf("HTTP error: status code %d", resp.StatusCode)}
+		p.Info("Starting to process streaming response")
+		if p.ProcessResponse != nil {
+			p.ProcessResponse(ctx, resp, respChan)
 			return
 		}
-
-		p.Info("Starting to process streaming response")
 		p.ProcessStreamableResponse(ctx, resp, respChan)
 	}()
 
-	p.Info("Ollama CallChatCompletionsStream launched goroutine")
 	return respChan
 }
 
+// doWithRetry sends the request until the provider answers 200, retrying with the retry policy.
+// The error of a non-200 answer is an HTTPError holding the error returned by the provider.
+func (p *BaseProvider) doWithRetry(ctx context.Context, req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		// NOTE: The body of the request is consumed by each attempt
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		resp, err := p.Client.Do(req)
+		if err == nil {
+			p.Infof("Response status: %d", resp.StatusCode)
+			if resp.StatusCode == http.StatusOK {
+				return resp, nil
+			}
+
+			err = newHTTPError(resp)
+			resp.Body.Close()
+		}
+
+		delay, retry := p.Retry.delay(attempt, err)
+		if !retry || ctx.Err() != nil {
+			return nil, err
+		}
+
+		p.Warnf("Request failed: %v, retrying in %s (%d/%d)", err, delay, attempt+1, p.Retry.MaxRetries)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
 //nolint:cyclop
 func (p *BaseProvider) PrepareMessagesForCompletion(
 	model string, messages []*Message, systemPrompt *string,
@@ -120,7 +186,10 @@ func (p *BaseProvider) PrepareMessagesForCompletion(
 	}
 
 	// Add original messages
-	for _, msg := range messages {
+	// NOTE: The messages are copied, the history keeps its timestamps
+	for _, original := range messages {
+		msg := *original
+
 		// Handle content conversion for structured content
 		if parts, ok := msg.Content.([]*ContentPart); ok {
 			var apiParts []map[string]any
@@ -137,11 +206,9 @@ func (p *BaseProvider) PrepareMessagesForCompletion(
 		msg.Timestamp = nil
 		msg.UnixTimestamp = 0
 
-		preparedMessages = append(preparedMessages, msg)
+		preparedMessages = append(preparedMessages, &msg)
 	}
 
-	p.Infof("Prepared messages for completion: %v", preparedMessages)
-
 	return preparedMessages
 }
 
@@ -153,6 +220,7 @@ func (p *BaseProvider) ProcessStreamableResponse(
 	// Process streaming response
 	scanner := bufio.NewScanner(resp.Body)
 	lineCount := 0
+	finished := false
 	for scanner.Scan() {
 		select {
 		case <-ctx.Done():
@@ -165,7 +233,7 @@ func (p *BaseProvider) ProcessStreamableResponse(
 
 		line := scanner.Text()
 		lineCount++
-		p.Debugf("Received line %d: %s", lineCount, line)
+		p.debugContent("Received line %d: %s", lineCount, line)
 
 		// NOTE: Ignore non-data lines
 		if line == "" || !strings.HasPrefix(line, "data: ") {
@@ -184,60 +252,29 @@ func (p *BaseProvider) ProcessStreamableResponse(
 			continue
 		}
 
+		// NOTE: The usage is sent in the last chunk, after the one with the finish reason
+		if response.Usage != nil {
+			respChan <- StreamChunk{ID: response.ID, Model: response.Model, Usage: response.Usage.usage()}
+		}
+
 		if len(response.Choices) > 0 {
 			choice := response.Choices[0]
 
-			// NOTE: stream chunk DONE
-			if choice.FinishReason != "" {
-				p.Info("Stream marked as done")
-
-				// 非优先使用 reasoning_content，如果为空则使用 content
-				var finalContent string
-				if choice.Delta != nil {
-					if choice.Delta.Content != "" {
-						finalContent = choice.Delta.Content
-					} else {
-						finalContent = choice.Delta.ReasoningContent
-					}
-				}
-
-				if finalContent != "" {
-					p.Debugf("Sending final chunk: %s", finalContent)
-					respChan <- StreamChunk{
-						ID:    response.ID,
-						Model: response.Model,
-
-						Content: finalContent,
-						Done:    true,
-					}
-				} else {
-					p.Debugln("Sending done signal")
-					respChan <- StreamChunk{
-						ID:    response.ID,
-						Model: response.Model,
+			// NOTE: Send stream chunk to response channel, the reasoning is kept apart from the answer
+			if choice.Delta != nil {
+				respChan <- StreamChunk{
+					ID:    response.ID,
+					Model: response.Model,
 
-						Done: true,
-					}
+					Content:          choice.Delta.content(),
+					ReasoningContent: choice.Delta.reasoning(),
+					ToolCalls:        choice.Delta.toolCallDeltas(),
 				}
-
-				break
 			}
 
-			// NOTE: Send stream chunk to response channel
-			// 优先使用 reasoning_content，如果为空则使用 content
-			var chunkContent string
-			if choice.Delta.Content != "" {
-				chunkContent = choice.Delta.Content
-			} else {
-				chunkContent = choice.Delta.ReasoningContent
-			}
-
-			respChan <- StreamChunk{
-				ID:    response.ID,
-				Model: response.Model,
-
-				Content: chunkContent,
-				Done:    false,
+			if choice.FinishReason != "" {
+				p.Infof("Stream finished: %s", choice.FinishReason)
+				finished = true
 			}
 		}
 	}
@@ -247,90 +284,17 @@ func (p *BaseProvider) ProcessStreamableResponse(
 	if err := scanner.Err(); err != nil {
 		p.Errorf("Scanner error: %v", err)
 		respChan <- StreamChunk{Error: fmt.Errorf("error reading response stream: %w", err)}
+		return
 	} else if lineCount == 0 {
 		p.Warn("No lines received from response body - this might indicate an empty response")
 	}
-}
 
-func (p *BaseProvider) HandleStreamableChat(streamCh <-chan StreamChunk) LLMStreamRet {
-	// NOTE Check channel is closed or not
-	chunk, ok := <-streamCh
-	if !ok {
-		p.Info("Stream channel closed immediately")
-		return LLMStreamRet{}
-	}
-
-	// NOTE: 1. Error is occurred => return
-	if chunk.Error != nil {
-		p.Errorln("Stream error:", chunk.Error)
-		return LLMStreamRet{Err: chunk.Error}
-	}
-
-	// NOTE: 2. Process Done => return
-	if chunk.Done {
-		p.Info("Stream completed immediately")
-		return LLMStreamRet{
-			ID:    chunk.ID,
-			Model: chunk.Model,
-			Done:  true,
-		}
-	}
-
-	// NOTE: 3. Processing
-	if chunk.Content != "" {
-		p.Infof("Received first chunk: %s", chunk.Content)
-		return LLMStreamRet{
-			ID:    chunk.ID,
-			Model: chunk.Model,
-
-			Content:  chunk.Content,
-			StreamCh: streamCh,
-		}
-	}
-
-	// NOTE: 4. Empty first chunk
-	p.Info("Empty first chunk, waiting for next")
-	return p.waitForNextChunk(streamCh)
-}
-
-func (p *BaseProvider) waitForNextChunk(streamCh <-chan StreamChunk) LLMStreamRet {
-	// NOTE Check channel is closed or not
-	chunk, ok := <-streamCh
-	if !ok {
-		p.Info("Stream channel closed => complete!!!")
-		return LLMStreamRet{}
-	}
-
-	if chunk.Error != nil {
-		p.Errorln("Stream error:", chunk.Error)
-		return LLMStreamRet{Err: chunk.Error}
-	}
-
-	if chunk.Done {
-		p.Info("Stream completed")
-		return LLMStreamRet{
-			ID:    chunk.ID,
-			Model: chunk.Model,
-
-			Done: true,
-		}
-	}
-
-	if chunk.Content != "" {
-		return LLMStreamRet{
-			ID:    chunk.ID,
-			Model: chunk.Model,
-
-			Content:  chunk.Content,
-			StreamCh: streamCh,
-		}
-	}
-
-	p.Debug("Empty chunk, waiting for next")
-	return p.waitForNextChunk(streamCh)
+	// NOTE: stream chunk DONE
+	respChan <- StreamChunk{Done: finished}
 }
 
 func (p *BaseProvider) CallStreamableChatCompletions(
+	ctx context.Context,
 	provider string,
 	reasoningEffort string,
 	messages []*Message,
@@ -341,44 +305,62 @@ func (p *BaseProvider) CallStreamableChatCompletions(
 		[]*Message,
 		*string,
 	) (*http.Request, error),
-) *Message {
-	ret := p.HandleStreamableChat(p.DoCallStreamableChatCompletions(messages, prompt, BuildRequest))
-	if ret.Err != nil {
-		p.Error(ret.Err)
-		return nil
-	}
-
-	if ret.Done {
-		p.Info("Chat completed")
-		return nil
-	}
-
-	var fullContent strings.Builder
-	fullContent.WriteString(ret.Content)
-
-	var id, model string
-	if ret.StreamCh != nil {
-		for chunk := range ret.StreamCh {
-			if chunk.Error != nil {
-				p.Errorln("Stream error:", chunk.Error)
-				break
+) (*Message, error) {
+	var (
+		fullContent strings.Builder
+		reasoning   strings.Builder
+		signature   string
+		usage       *Usage
+		toolCalls   toolCallBuilder
+		id, model   string
+	)
+
+	for chunk := range p.DoCallStreamableChatCompletions(ctx, messages, prompt, BuildRequest) {
+		if chunk.Error != nil {
+			p.Errorln("Stream error:", chunk.Error)
+
+			// NOTE: The transport error of the aborted request does not always wrap the context error
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("%s: %w", provider, ctx.Err())
 			}
+			return nil, fmt.Errorf("%s: %w", provider, chunk.Error)
+		}
 
-			if chunk.Content != "" {
-				fullContent.WriteString(chunk.Content)
-				p.Debugf("Assistant chunk: %s", chunk.Content)
-			}
+		if chunk.ID != "" {
+			id = chunk.ID
+		}
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
 
-			if chunk.Done {
-				p.Info("Stream completed")
-				id, model = chunk.ID, chunk.Model
-				break
-			}
+		if chunk.Content != "" {
+			fullContent.WriteString(chunk.Content)
+			p.debugContent("Assistant chunk: %s", chunk.Content)
+			EmitEvent(ctx, &Event{Type: EventTextDelta, Delta: chunk.Content})
+		}
+		if chunk.ReasoningContent != "" {
+			reasoning.WriteString(chunk.ReasoningContent)
+			EmitEvent(ctx, &Event{Type: EventReasoningDelta, Delta: chunk.ReasoningContent})
+		}
+		if chunk.ReasoningSignature != "" {
+			signature = chunk.ReasoningSignature
+		}
+		toolCalls.add(chunk.ToolCalls)
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+
+		if chunk.Done {
+			p.Info("Stream completed")
+			break
 		}
 	}
 
 	contentFull := fullContent.String()
-	p.Infof("Assistant: %s", contentFull)
+	p.debugContent("Assistant: %s", contentFull)
+	if contentFull == "" && len(toolCalls.calls) == 0 {
+		return nil, fmt.Errorf("%s: %w", provider, ErrEmptyResponse)
+	}
 
 	assistantMessage := NewMessageWithOption(
 		RoleAssistant,
@@ -387,48 +369,110 @@ func (p *BaseProvider) CallStreamableChatCompletions(
 			ID:    id,
 			Model: model,
 
-			ReasoningContent: contentFull,
-			Provider:         provider,
-			ReasoningEffort:  reasoningEffort,
-			Links:            nil,
+			ReasoningContent:   reasoning.String(),
+			ReasoningSignature: signature,
+			Provider:           provider,
+			ReasoningEffort:    reasoningEffort,
+			Links:              nil,
+			Usage:              usage,
 		})
+	assistantMessage.ToolCalls = toolCalls.calls
 	// p.Infof("Assistant: %s", assistantMessage.Content)
 
-	return assistantMessage
+	return assistantMessage, nil
 }
 
-// GenerateCurlCommand returns a string that can be executed to make the request
+// debugContent logs the content of the conversation at debug level, only if DebugHTTP is set
+func (p *BaseProvider) debugContent(template string, args ...any) {
+	if p.DebugHTTP {
+		p.Debugf(template, args...)
+	}
+}
+
+// toolCallBuilder assembles the native tool calls from the fragments streamed by the provider
+type toolCallBuilder struct {
+	calls   []*ToolCall
+	byIndex map[int]*ToolCall
+}
+
+func (b *toolCallBuilder) add(deltas []*ToolCallDelta) {
+	for _, delta := range deltas {
+		if b.byIndex == nil {
+			b.byIndex = make(map[int]*ToolCall)
+		}
+
+		call, ok := b.byIndex[delta.Index]
+		if !ok {
+			call = &ToolCall{Type: ToolCallTypeFunction}
+			b.byIndex[delta.Index] = call
+			b.calls = append(b.calls, call)
+		}
+
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Name != "" {
+			call.Function.Name = delta.Name
+		}
+		call.Function.Arguments += delta.Arguments
+	}
+}
+
+// GenerateCurlCommand returns a string that can be executed to make the request.
+// The auth headers, the API keys and the sensitive fields are masked,
+// the body is only included if DebugHTTP is set.
 // ⚠️ 注意：因为该函数没有读取 req.Body => 请求体仍然可以被 client.Do 正常读取。
-func (*BaseProvider) GenerateCurlCommand(
+func (p *BaseProvider) GenerateCurlCommand(
 	req *http.Request,
 	bodyBytes []byte,
 ) (string, error) {
+	redactor := p.Redactor
+	if redactor == nil {
+		redactor = NewRedactor(nil)
+	}
+
 	var command strings.Builder
 
 	// 1. Add 'curl -X METHOD' part
 	// 对 URL 进行转义处理
-	command.WriteString(fmt.Sprintf("curl -X %s %s", req.Method, shellEscape(req.URL.String())))
+	command.WriteString(fmt.Sprintf("curl -X %s %s", req.Method, shellEscape(redactor.URL(req.URL))))
+
+	// 2. Add req headers, sorted to keep the command stable
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-	// 2. Add req headers
-	for key, values := range req.Header {
-		for _, value := range values {
+	for _, key := range keys {
+		for _, value := range req.Header[key] {
 			// 使用 ' \\\n  -H' 来换行和缩进，使命令更易读
 			// 对 header 的 key 和 value 进行转义
-			headerStr := fmt.Sprintf("%s: %s", key, value)
+			headerStr := fmt.Sprintf("%s: %s", key, redactor.Header(key, value))
 			command.WriteString(" \\\n  -H " + shellEscape(headerStr))
 		}
 	}
 
 	// 3. Add req body if exists
-	if len(bodyBytes) > 0 {
+	switch {
+	case len(bodyBytes) == 0:
+	case p.DebugHTTP:
 		// Use ' \\\n  --data-raw' to make command more readable
 		// 对请求体进行转义处理
-		command.WriteString(" \\\n  --data-raw " + shellEscape(string(bodyBytes)))
+		command.WriteString(" \\\n  --data-raw " + shellEscape(redactor.Body(bodyBytes)))
+	default:
+		command.WriteString(fmt.Sprintf(" \\\n  --data-raw '<%d bytes, set debug_http to log the body>'", len(bodyBytes)))
 	}
 
 	return command.String(), nil
 }
 
+// logRequest logs the request as a replayable curl command, see GenerateCurlCommand
+func (p *BaseProvider) logRequest(req *http.Request, body []byte) {
+	curlCmd, _ := p.GenerateCurlCommand(req, body)
+	p.Infof("--- Replayable curl command ---\n%s\n-----------------------------", curlCmd)
+}
+
 // shellEscape 对字符串进行 shell 转义处理
 func shellEscape(s string) string {
 	// 如果字符串不包含需要转义的字符，直接用单引号包围
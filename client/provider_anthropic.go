@@ -0,0 +1,529 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/kydenul/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
+)
+
+const (
+	DefaultAnthropicAPIPath = "/v1/messages"
+	AnthropicVersion        = "2023-06-01"
+
+	ModelClaude37 = "claude-3-7"
+
+	AnthropicBlockText       = "text"
+	AnthropicBlockThinking   = "thinking"
+	AnthropicBlockToolUse    = "tool_use"
+	AnthropicBlockToolResult = "tool_result"
+)
+
+var (
+	_ Provider         = (*AnthropicProvider)(nil)
+	_ NativeToolCaller = (*AnthropicProvider)(nil)
+
+	// AnthropicThinkingBudget 是 reasoning_effort 对应的 extended thinking token 预算，
+	// 不在表中的值 (如 minimal) 不开启 extended thinking
+	AnthropicThinkingBudget = map[string]uint64{
+		"high":   16384,
+		"medium": 8192,
+		"low":    2048,
+	}
+)
+
+// AnthropicRequest 是用于发送 Anthropic /v1/messages 请求的结构体
+type AnthropicRequest struct {
+	Model     string                   `json:"model"`
+	MaxTokens uint64                   `json:"max_tokens"`       // 必填
+	System    []*AnthropicContentBlock `json:"system,omitempty"` // system prompt 是顶层字段，不在 messages 中
+	Messages  []*AnthropicMessage      `json:"messages"`
+	Stream    bool                     `json:"stream"`
+
+	Thinking *AnthropicThinking `json:"thinking,omitempty"` // extended thinking
+	Tools    []*AnthropicTool   `json:"tools,omitempty"`    // Native tool use
+}
+
+// AnthropicThinking 开启 extended thinking，budget_tokens 至少为 1024 且小于 max_tokens
+type AnthropicThinking struct {
+	Type         string `json:"type"` // Always "enabled"
+	BudgetTokens uint64 `json:"budget_tokens"`
+}
+
+// AnthropicTool 描述一个可供模型调用的工具
+type AnthropicTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+// AnthropicMessage 是 messages 数组的元素，content 是字符串或 content block 数组
+type AnthropicMessage struct {
+	Role    string `json:"role"` // user | assistant
+	Content any    `json:"content"`
+}
+
+// AnthropicContentBlock 是请求和响应中的 content block，字段的含义取决于 type
+type AnthropicContentBlock struct {
+	Type string `json:"type"` // text | thinking | tool_use | tool_result
+
+	Text      string `json:"text,omitempty"`      // text
+	Thinking  string `json:"thinking,omitempty"`  // thinking
+	Signature string `json:"signature,omitempty"` // thinking, 必须原样发回
+	ID        string `json:"id,omitempty"`        // tool_use
+	Name      string `json:"name,omitempty"`      // tool_use
+	Input     any    `json:"input,omitempty"`     // tool_use, JSON 对象
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"` // tool_result
+
+	CacheControl map[string]any `json:"cache_control,omitempty"` // Prompt caching
+}
+
+// AnthropicResponse 是非流式响应，也是流式响应 message_start 事件中的 message
+type AnthropicResponse struct {
+	ID         string                   `json:"id"`
+	Model      string                   `json:"model"`
+	Content    []*AnthropicContentBlock `json:"content"`
+	StopReason string                   `json:"stop_reason"`
+	Usage      *AnthropicUsage          `json:"usage,omitempty"`
+}
+
+// AnthropicUsage 是响应的 token 用量，message_delta 事件中的 output_tokens 是累计值
+type AnthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// usage converts the token usage, the prompt tokens include the cached ones
+func (u *AnthropicUsage) usage() *Usage {
+	return &Usage{
+		PromptTokens:     u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+		CompletionTokens: u.OutputTokens,
+	}
+}
+
+// AnthropicStreamEvent 是流式响应中每个 SSE 事件的 data，type 与 event 行相同:
+// message_start | content_block_start | content_block_delta | content_block_stop |
+// message_delta | message_stop | ping | error
+type AnthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Index int    `json:"index"` // content block 的序号
+
+	Message      *AnthropicResponse     `json:"message,omitempty"`       // message_start
+	ContentBlock *AnthropicContentBlock `json:"content_block,omitempty"` // content_block_start
+	Delta        *AnthropicStreamDelta  `json:"delta,omitempty"`         // content_block_delta, message_delta
+	Usage        *AnthropicUsage        `json:"usage,omitempty"`         // message_delta
+	Error        *AnthropicError        `json:"error,omitempty"`         // error
+}
+
+// AnthropicStreamDelta 是 content block 的增量: text_delta | thinking_delta | signature_delta | input_json_delta
+type AnthropicStreamDelta struct {
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	Thinking    string `json:"thinking"`
+	Signature   string `json:"signature"`
+	PartialJSON string `json:"partial_json"` // tool_use 的 input 片段，需要按 index 拼接
+	StopReason  string `json:"stop_reason"`  // message_delta
+}
+
+// AnthropicError 是 error 事件中的错误
+type AnthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type AnthropicProvider struct {
+	BaseProvider
+
+	config *Config
+}
+
+func NewAnthropicProvider(config *Config, logger log.Logger) *AnthropicProvider {
+	p := &AnthropicProvider{
+		BaseProvider: BaseProvider{
+			Logger: logger,
+			Client: NewHTTPClient(),
+			Retry:  NewRetryPolicy(config),
+
+			Redactor:  NewRedactor(config),
+			DebugHTTP: config.DebugHTTP,
+		},
+		config: config,
+	}
+	p.ProcessResponse = p.processResponse
+
+	return p
+}
+
+func (p *AnthropicProvider) BuildRequest(
+	ctx context.Context,
+	respChan chan StreamChunk,
+	messages []*Message,
+	systemPrompt *string,
+) (*http.Request, error) {
+	return p.buildRequest(ctx, respChan, messages, systemPrompt, nil)
+}
+
+func (p *AnthropicProvider) buildRequest(
+	ctx context.Context,
+	respChan chan StreamChunk,
+	messages []*Message,
+	systemPrompt *string,
+	tools []*mcp.Tool,
+) (*http.Request, error) {
+	p.Infof("Starting Anthropic stream request")
+	// NOTE: The system prompt is the top level field instead of a message
+	preparedMessages := p.PrepareMessagesForCompletion(p.config.Model, messages, nil)
+
+	body := AnthropicRequest{
+		Model:     p.config.Model,
+		MaxTokens: p.config.MaxTokens,
+		Messages:  anthropicMessages(preparedMessages),
+		Stream:    p.config.Stream,
+		Tools: lo.Map(tools, func(tool *mcp.Tool, _ int) *AnthropicTool {
+			return &AnthropicTool{
+				Name:        tool.Name,
+				Description: tool.Description,
+				InputSchema: tool.InputSchema,
+			}
+		}),
+	}
+	if body.MaxTokens == 0 {
+		body.MaxTokens = DefaultMaxTokens
+	}
+
+	// NOTE: The cache breakpoint at the end of the system prompt caches the tools and the system prompt,
+	// which carries the long MCP prompt in xml mode
+	if systemPrompt != nil && *systemPrompt != "" {
+		body.System = []*AnthropicContentBlock{{
+			Type:         AnthropicBlockText,
+			Text:         *systemPrompt,
+			CacheControl: map[string]any{"type": "ephemeral"},
+		}}
+	}
+
+	if budget, ok := AnthropicThinkingBudget[p.config.ReasoningEffort]; ok &&
+		budget < body.MaxTokens && anthropicSupportsThinking(p.config.Model) {
+		body.Thinking = &AnthropicThinking{Type: "enabled", BudgetTokens: budget}
+	}
+
+	jsonBody, err := sonic.Marshal(body)
+	if err != nil {
+		p.Errorf("Error marshaling request body: %v", err)
+		respChan <- StreamChunk{Error: fmt.Errorf("error marshaling request body: %w", err)}
+		return nil, fmt.Errorf("error marshaling request body: %w", err)
+	}
+
+	// Build URL
+	// NOTE: custom_api_path defaults to the OpenAI path, which is not served by Anthropic
+	url := p.config.BaseURL
+	if p.config.CustomAPIPath != "" && p.config.CustomAPIPath != DefaultCustomAPIPath {
+		url += p.config.CustomAPIPath
+	} else {
+		url += DefaultAnthropicAPIPath
+	}
+
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		respChan <- StreamChunk{Error: fmt.Errorf("error creating request: %w", err)}
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	// Set headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", p.config.APIKey)
+	req.Header.Set("Anthropic-Version", AnthropicVersion)
+
+	p.logRequest(req, jsonBody)
+
+	return req, nil
+}
+
+func (p *AnthropicProvider) CallStreamableChatCompletions(
+	ctx context.Context,
+	messages []*Message,
+	prompt *string,
+) (*Message, error) {
+	return p.BaseProvider.CallStreamableChatCompletions(
+		ctx, p.config.Provider, p.config.ReasoningEffort, messages, prompt, p.BuildRequest)
+}
+
+// CallStreamableChatCompletionsWithTools calls /v1/messages with the MCP tools in the tools field
+func (p *AnthropicProvider) CallStreamableChatCompletionsWithTools(
+	ctx context.Context,
+	messages []*Message,
+	prompt *string,
+	tools []*mcp.Tool,
+) (*Message, error) {
+	return p.BaseProvider.CallStreamableChatCompletions(
+		ctx, p.config.Provider, p.config.ReasoningEffort, messages, prompt,
+		func(ctx context.Context, respChan chan StreamChunk, messages []*Message, prompt *string) (*http.Request, error) {
+			return p.buildRequest(ctx, respChan, messages, prompt, tools)
+		})
+}
+
+// processResponse parses the SSE events of /v1/messages, or the only message if stream is false
+func (p *AnthropicProvider) processResponse(
+	ctx context.Context,
+	resp *http.Response,
+	respChan chan StreamChunk,
+) {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		p.processMessage(resp.Body, respChan)
+		return
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 16*bufio.MaxScanTokenSize)
+
+	lineCount := 0
+	var usage *AnthropicUsage
+	for scanner.Scan() {
+		select {
+		case <-ctx.Done():
+			p.Info("Context cancelled")
+			respChan <- StreamChunk{Error: ctx.Err()}
+			return
+
+		default:
+		}
+
+		line := scanner.Text()
+		lineCount++
+		p.debugContent("Received line %d: %s", lineCount, line)
+
+		// NOTE: The type of the event is also in the data, ignore the event lines
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		event := &AnthropicStreamEvent{}
+		if err := sonic.UnmarshalString(strings.TrimSpace(strings.TrimPrefix(line, "data:")), event); err != nil {
+			p.Errorf("Error unmarshaling response line: %v", err)
+			continue
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				usage = event.Message.Usage
+				respChan <- StreamChunk{ID: event.Message.ID, Model: event.Message.Model}
+			}
+
+		case "content_block_start":
+			if event.ContentBlock != nil && event.ContentBlock.Type == AnthropicBlockToolUse {
+				respChan <- StreamChunk{ToolCalls: []*ToolCallDelta{{
+					Index: event.Index,
+					ID:    event.ContentBlock.ID,
+					Name:  event.ContentBlock.Name,
+				}}}
+			}
+
+		case "content_block_delta":
+			if chunk, ok := anthropicDeltaChunk(event); ok {
+				respChan <- chunk
+			}
+
+		case "message_delta":
+			if usage != nil && event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+
+		case "message_stop":
+			p.Info("Stream marked as done")
+			chunk := StreamChunk{Done: true}
+			if usage != nil {
+				chunk.Usage = usage.usage()
+			}
+			respChan <- chunk
+			return
+
+		case "error":
+			errMsg := "unknown error"
+			if event.Error != nil {
+				errMsg = event.Error.Type + ": " + event.Error.Message
+			}
+			p.Errorf("Anthropic error: %s", errMsg)
+			respChan <- StreamChunk{Error: fmt.Errorf("anthropic error: %s", errMsg)}
+			return
+
+		default: // ping, content_block_stop
+		}
+	}
+
+	p.Infof("Finished scanning response body, total lines: %d", lineCount)
+
+	if err := scanner.Err(); err != nil {
+		p.Errorf("Scanner error: %v", err)
+		respChan <- StreamChunk{Error: fmt.Errorf("error reading response stream: %w", err)}
+	}
+}
+
+// anthropicDeltaChunk converts a content_block_delta event to a stream chunk
+func anthropicDeltaChunk(event *AnthropicStreamEvent) (StreamChunk, bool) {
+	if event.Delta == nil {
+		return StreamChunk{}, false
+	}
+
+	switch event.Delta.Type {
+	case "text_delta":
+		return StreamChunk{Content: event.Delta.Text}, true
+	case "thinking_delta":
+		return StreamChunk{ReasoningContent: event.Delta.Thinking}, true
+	case "signature_delta":
+		return StreamChunk{ReasoningSignature: event.Delta.Signature}, true
+	case "input_json_delta":
+		return StreamChunk{ToolCalls: []*ToolCallDelta{{
+			Index:     event.Index,
+			Arguments: event.Delta.PartialJSON,
+		}}}, true
+	default:
+		return StreamChunk{}, false
+	}
+}
+
+// processMessage parses the response of /v1/messages if stream is false
+func (p *AnthropicProvider) processMessage(body io.Reader, respChan chan StreamChunk) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		respChan <- StreamChunk{Error: fmt.Errorf("error reading response: %w", err)}
+		return
+	}
+
+	response := &AnthropicResponse{}
+	if err := sonic.Unmarshal(data, response); err != nil {
+		respChan <- StreamChunk{Error: fmt.Errorf("error unmarshaling response: %w", err)}
+		return
+	}
+
+	chunk := StreamChunk{ID: response.ID, Model: response.Model, Done: true}
+	if response.Usage != nil {
+		chunk.Usage = response.Usage.usage()
+	}
+	for idx, block := range response.Content {
+		switch block.Type {
+		case AnthropicBlockText:
+			chunk.Content += block.Text
+
+		case AnthropicBlockThinking:
+			chunk.ReasoningContent += block.Thinking
+			chunk.ReasoningSignature = block.Signature
+
+		case AnthropicBlockToolUse:
+			args, err := sonic.MarshalString(block.Input)
+			if err != nil {
+				p.Errorf("Error marshaling tool use input: %v", err)
+				continue
+			}
+			chunk.ToolCalls = append(chunk.ToolCalls, &ToolCallDelta{
+				Index: idx, ID: block.ID, Name: block.Name, Arguments: args,
+			})
+		}
+	}
+
+	respChan <- chunk
+}
+
+// anthropicSupportsThinking reports whether the model supports extended thinking,
+// which is not supported by the claude-3 models before claude-3-7
+func anthropicSupportsThinking(model string) bool {
+	return !strings.Contains(model, ModelClaude3) || strings.Contains(model, ModelClaude37)
+}
+
+// anthropicMessages converts the messages to the Anthropic format. The native tool calls become
+// tool_use blocks of the assistant, and the tool results become tool_result blocks of the user,
+// the results of the same turn are merged into one user message.
+func anthropicMessages(messages []*Message) []*AnthropicMessage {
+	converted := make([]*AnthropicMessage, 0, len(messages))
+
+	for _, msg := range messages {
+		switch {
+		case msg.Role == RoleSystem:
+			continue
+
+		case msg.Role == RoleTool && msg.ToolCallID != "":
+			block := &AnthropicContentBlock{
+				Type:      AnthropicBlockToolResult,
+				ToolUseID: msg.ToolCallID,
+				Content:   cast.ToString(msg.Content),
+			}
+
+			if len(converted) > 0 {
+				last := converted[len(converted)-1]
+				if blocks, ok := last.Content.([]*AnthropicContentBlock); ok && last.Role == RoleUser &&
+					len(blocks) > 0 && blocks[0].Type == AnthropicBlockToolResult {
+					last.Content = append(blocks, block)
+					continue
+				}
+			}
+			converted = append(converted, &AnthropicMessage{
+				Role: RoleUser, Content: []*AnthropicContentBlock{block},
+			})
+
+		case msg.Role == RoleTool: // The result of the XML tool use
+			converted = append(converted, &AnthropicMessage{Role: RoleUser, Content: msg.Content})
+
+		case msg.Role == RoleAssistant && (len(msg.ToolCalls) > 0 || anthropicSignature(msg) != ""):
+			converted = append(converted, &AnthropicMessage{Role: RoleAssistant, Content: anthropicAssistantBlocks(msg)})
+
+		default:
+			converted = append(converted, &AnthropicMessage{Role: msg.Role, Content: msg.Content})
+		}
+	}
+
+	return converted
+}
+
+// anthropicAssistantBlocks returns the thinking, text and tool_use blocks of the assistant message.
+// NOTE: The thinking block must be sent back with its signature before the tool_use blocks.
+func anthropicAssistantBlocks(msg *Message) []*AnthropicContentBlock {
+	blocks := make([]*AnthropicContentBlock, 0, len(msg.ToolCalls)+2)
+
+	if anthropicSignature(msg) != "" {
+		blocks = append(blocks, &AnthropicContentBlock{
+			Type:      AnthropicBlockThinking,
+			Thinking:  msg.ReasoningContent,
+			Signature: msg.ReasoningSignature,
+		})
+	}
+
+	if text := cast.ToString(msg.Content); text != "" {
+		blocks = append(blocks, &AnthropicContentBlock{Type: AnthropicBlockText, Text: text})
+	}
+
+	for _, call := range msg.ToolCalls {
+		input := make(map[string]any)
+		_ = sonic.UnmarshalString(call.Function.Arguments, &input)
+
+		blocks = append(blocks, &AnthropicContentBlock{
+			Type:  AnthropicBlockToolUse,
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Input: input,
+		})
+	}
+
+	return blocks
+}
+
+// anthropicSignature returns the thinking signature of the message, the signatures of the other providers
+// are not accepted by Anthropic
+func anthropicSignature(msg *Message) string {
+	if msg.Provider != ProviderAnthropic {
+		return ""
+	}
+
+	return msg.ReasoningSignature
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bytedance/sonic"
+)
+
+// anthropicStub is a stand-in of /v1/messages answering the n-th request with the n-th list of SSE events
+type anthropicStub struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*AnthropicRequest
+	headers  []http.Header
+}
+
+func newAnthropicStub(t *testing.T, responses ...[]string) *anthropicStub {
+	stub := &anthropicStub{}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != DefaultAnthropicAPIPath {
+			t.Errorf("request path = %s, want %s", r.URL.Path, DefaultAnthropicAPIPath)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		request := &AnthropicRequest{}
+		if err := sonic.Unmarshal(body, request); err != nil {
+			t.Errorf("failed to parse request: %v", err)
+		}
+
+		stub.mu.Lock()
+		idx := len(stub.requests)
+		stub.requests = append(stub.requests, request)
+		stub.headers = append(stub.headers, r.Header.Clone())
+		stub.mu.Unlock()
+
+		if idx >= len(responses) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range responses[idx] {
+			event := &AnthropicStreamEvent{}
+			_ = sonic.UnmarshalString(data, event)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+	}))
+	t.Cleanup(stub.Close)
+
+	return stub
+}
+
+func anthropicTextEvents(id, text string) []string {
+	return []string{
+		fmt.Sprintf(`{"type":"message_start","message":{"id":%q,"model":"test-model","content":[]}}`, id),
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"ping"}`,
+		fmt.Sprintf(`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":%q}}`, text),
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"}}`,
+		`{"type":"message_stop"}`,
+	}
+}
+
+func TestManager_AnthropicNativeToolUse(t *testing.T) {
+	stub := newAnthropicStub(t,
+		[]string{
+			`{"type":"message_start","message":{"id":"msg_1","model":"test-model","content":[]}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Use echo."}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"content_block_start","index":1,` +
+				`"content_block":{"type":"tool_use","id":"toolu_1","name":"echo","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"text\":"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"hi\"}"}}`,
+			`{"type":"content_block_stop","index":1}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"}}`,
+			`{"type":"message_stop"}`,
+		},
+		anthropicTextEvents("msg_2", "The tool said echo: hi"),
+	)
+	mgr := newTestManager(t, ProviderAnthropic, stub.URL, ToolCallModeNative)
+
+	msg, err := mgr.HandleUserTextInput(t.Context(), "echo hi")
+	if err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+	if msg.Content != "The tool said echo: hi" || msg.ID != "msg_2" {
+		t.Errorf("final message = %q (%s), want the text of msg_2", msg.Content, msg.ID)
+	}
+
+	if len(stub.requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(stub.requests))
+	}
+	if got := stub.headers[0].Get("Anthropic-Version"); got != AnthropicVersion {
+		t.Errorf("anthropic-version = %q, want %q", got, AnthropicVersion)
+	}
+
+	first := stub.requests[0]
+	if len(first.System) != 1 || first.System[0].CacheControl["type"] != "ephemeral" {
+		t.Errorf("system = %+v, want one cached text block", first.System)
+	}
+	if len(first.Messages) != 1 || first.Messages[0].Role != RoleUser {
+		t.Errorf("messages = %+v, want only the user message", first.Messages)
+	}
+	if len(first.Tools) != 2 || first.Tools[0].Name != "echo" || first.Tools[0].InputSchema == nil {
+		t.Errorf("tools = %+v, want the tools of the test server with their input schema", first.Tools)
+	}
+	if first.MaxTokens == 0 {
+		t.Error("max_tokens is required")
+	}
+
+	// NOTE: The thinking block is sent back with its signature, followed by the tool use and its result.
+	// The content is decoded as maps, sonic.ConfigStd marshals their keys in order.
+	second, err := sonic.ConfigStd.MarshalToString(stub.requests[1].Messages)
+	if err != nil {
+		t.Fatalf("failed to marshal messages: %v", err)
+	}
+	for _, want := range []string{
+		`"role":"assistant","content":[{"signature":"sig","thinking":"Use echo.","type":"thinking"},` +
+			`{"id":"toolu_1","input":{"text":"hi"},"name":"echo","type":"tool_use"}]`,
+		`"role":"user","content":[{"content":"echo: hi","tool_use_id":"toolu_1","type":"tool_result"}]`,
+	} {
+		if !strings.Contains(second, want) {
+			t.Errorf("second request messages = %s, want %s", second, want)
+		}
+	}
+}
+
+func TestAnthropicProvider_Error(t *testing.T) {
+	stub := newAnthropicStub(t, []string{
+		`{"type":"message_start","message":{"id":"msg_1","model":"test-model","content":[]}}`,
+		`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+	})
+	provider := NewAnthropicProvider(&Config{
+		Provider: ProviderAnthropic,
+		BaseURL:  stub.URL,
+		Model:    "test-model",
+		Stream:   true,
+	}, &discardLogger{})
+
+	_, err := provider.CallStreamableChatCompletions(t.Context(), []*Message{NewMessageWithOption(RoleUser, "hi", nil)}, nil)
+	if err == nil || !strings.Contains(err.Error(), "overloaded_error: Overloaded") {
+		t.Errorf("CallStreamableChatCompletions() error = %v, want the overloaded error", err)
+	}
+}
+
+func TestAnthropicProvider_Thinking(t *testing.T) {
+	tests := []struct {
+		name            string
+		model           string
+		reasoningEffort string
+		maxTokens       uint64
+		want            uint64
+	}{
+		{"medium", "claude-sonnet-4-5", "medium", 0, 8192},
+		{"minimal disables thinking", "claude-sonnet-4-5", "minimal", 0, 0},
+		{"budget exceeds max_tokens", "claude-sonnet-4-5", "high", 4096, 0},
+		{"claude-3-7 supports thinking", "claude-3-7-sonnet-latest", "low", 0, 2048},
+		{"claude-3-5 does not support thinking", "claude-3-5-haiku-latest", "high", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newAnthropicStub(t, anthropicTextEvents("msg_1", "hello"))
+			provider := NewAnthropicProvider(&Config{
+				Provider:        ProviderAnthropic,
+				BaseURL:         stub.URL,
+				Model:           tt.model,
+				MaxTokens:       tt.maxTokens,
+				ReasoningEffort: tt.reasoningEffort,
+				Stream:          true,
+			}, &discardLogger{})
+
+			msg, err := provider.CallStreamableChatCompletions(
+				t.Context(), []*Message{NewMessageWithOption(RoleUser, "hi", nil)}, nil)
+			if err != nil {
+				t.Fatalf("CallStreamableChatCompletions() error = %v", err)
+			}
+			if msg.Content != "hello" {
+				t.Errorf("content = %q, want hello", msg.Content)
+			}
+
+			var got uint64
+			if thinking := stub.requests[0].Thinking; thinking != nil {
+				got = thinking.BudgetTokens
+			}
+			if got != tt.want {
+				t.Errorf("thinking budget = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
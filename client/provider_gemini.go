@@ -0,0 +1,452 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/kydenul/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
+)
+
+const (
+	DefaultGeminiAPIVersion = "/v1beta"
+
+	GeminiRoleUser  = "user"
+	GeminiRoleModel = "model"
+)
+
+var (
+	_ Provider         = (*GeminiProvider)(nil)
+	_ NativeToolCaller = (*GeminiProvider)(nil)
+)
+
+// GeminiRequest 是用于发送 Gemini generateContent / streamGenerateContent 请求的结构体
+type GeminiRequest struct {
+	Contents          []*GeminiContent `json:"contents"`
+	SystemInstruction *GeminiContent   `json:"systemInstruction,omitempty"` // system prompt 是顶层字段
+	Tools             []*GeminiTool    `json:"tools,omitempty"`             // Native function calling
+
+	GenerationConfig *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// GeminiGenerationConfig 是生成参数
+type GeminiGenerationConfig struct {
+	MaxOutputTokens uint64 `json:"maxOutputTokens,omitempty"`
+}
+
+// GeminiTool 是请求中 tools 数组的元素
+type GeminiTool struct {
+	FunctionDeclarations []*GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+// GeminiFunctionDeclaration 描述一个可供模型调用的函数，
+// parametersJsonSchema 接受完整的 JSON Schema，而 parameters 只接受 OpenAPI 的子集
+type GeminiFunctionDeclaration struct {
+	Name                 string `json:"name"`
+	Description          string `json:"description,omitempty"`
+	ParametersJSONSchema any    `json:"parametersJsonSchema,omitempty"`
+}
+
+// GeminiContent 是一轮对话，role 为 user 或 model
+type GeminiContent struct {
+	Role  string        `json:"role,omitempty"`
+	Parts []*GeminiPart `json:"parts"`
+}
+
+// GeminiPart 是 content 的一部分，text、functionCall、functionResponse 只有一个
+type GeminiPart struct {
+	Text             string `json:"text,omitempty"`
+	Thought          bool   `json:"thought,omitempty"`          // text 是推理内容
+	ThoughtSignature string `json:"thoughtSignature,omitempty"` // 推理签名，必须原样发回
+
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// GeminiFunctionCall 是模型请求的函数调用，每个调用都是完整的，通常没有 ID
+type GeminiFunctionCall struct {
+	ID   string         `json:"id,omitempty"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
+
+// GeminiFunctionResponse 是函数调用的结果，response 必须是 JSON 对象
+type GeminiFunctionResponse struct {
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+// GeminiResponse 是非流式响应，也是流式响应中每个 SSE 事件的 data
+type GeminiResponse struct {
+	Candidates     []*GeminiCandidate    `json:"candidates"`
+	PromptFeedback *GeminiPromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *GeminiUsageMetadata  `json:"usageMetadata,omitempty"` // 累计值
+	ModelVersion   string                `json:"modelVersion"`
+	ResponseID     string                `json:"responseId"`
+}
+
+// GeminiUsageMetadata 是响应的 token 用量，candidatesTokenCount 不包含推理 token
+type GeminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+}
+
+// usage converts the token usage, the completion tokens include the reasoning ones
+func (u *GeminiUsageMetadata) usage() *Usage {
+	return &Usage{
+		PromptTokens:     u.PromptTokenCount,
+		CompletionTokens: u.CandidatesTokenCount + u.ThoughtsTokenCount,
+		ReasoningTokens:  u.ThoughtsTokenCount,
+	}
+}
+
+// GeminiCandidate 是一个候选回答
+type GeminiCandidate struct {
+	Content      *GeminiContent `json:"content"`
+	FinishReason string         `json:"finishReason"` // 在最后一个数据块出现
+}
+
+// GeminiPromptFeedback 在 prompt 被拦截时给出原因
+type GeminiPromptFeedback struct {
+	BlockReason string `json:"blockReason"`
+}
+
+type GeminiProvider struct {
+	BaseProvider
+
+	config *Config
+}
+
+func NewGeminiProvider(config *Config, logger log.Logger) *GeminiProvider {
+	p := &GeminiProvider{
+		BaseProvider: BaseProvider{
+			Logger: logger,
+			Client: NewHTTPClient(),
+			Retry:  NewRetryPolicy(config),
+
+			Redactor:  NewRedactor(config),
+			DebugHTTP: config.DebugHTTP,
+		},
+		config: config,
+	}
+	p.ProcessResponse = p.processResponse
+
+	return p
+}
+
+func (p *GeminiProvider) BuildRequest(
+	ctx context.Context,
+	respChan chan StreamChunk,
+	messages []*Message,
+	systemPrompt *string,
+) (*http.Request, error) {
+	return p.buildRequest(ctx, respChan, messages, systemPrompt, nil)
+}
+
+func (p *GeminiProvider) buildRequest(
+	ctx context.Context,
+	respChan chan StreamChunk,
+	messages []*Message,
+	systemPrompt *string,
+	tools []*mcp.Tool,
+) (*http.Request, error) {
+	p.Infof("Starting Gemini stream request")
+	// NOTE: The system prompt is the systemInstruction instead of a content
+	preparedMessages := p.PrepareMessagesForCompletion(p.config.Model, messages, nil)
+
+	body := GeminiRequest{Contents: geminiContents(preparedMessages)}
+	if systemPrompt != nil && *systemPrompt != "" {
+		body.SystemInstruction = &GeminiContent{Parts: []*GeminiPart{{Text: *systemPrompt}}}
+	}
+	if len(tools) > 0 {
+		body.Tools = []*GeminiTool{{
+			FunctionDeclarations: lo.Map(tools, func(tool *mcp.Tool, _ int) *GeminiFunctionDeclaration {
+				return &GeminiFunctionDeclaration{
+					Name:                 tool.Name,
+					Description:          tool.Description,
+					ParametersJSONSchema: tool.InputSchema,
+				}
+			}),
+		}}
+	}
+	if p.config.MaxTokens > 0 {
+		body.GenerationConfig = &GeminiGenerationConfig{MaxOutputTokens: p.config.MaxTokens}
+	}
+
+	jsonBody, err := sonic.Marshal(body)
+	if err != nil {
+		p.Errorf("Error marshaling request body: %v", err)
+		respChan <- StreamChunk{Error: fmt.Errorf("error marshaling request body: %w", err)}
+		return nil, fmt.Errorf("error marshaling request body: %w", err)
+	}
+
+	// Build URL => {base_url}{api_version}/models/{model}:streamGenerateContent?alt=sse
+	// NOTE: custom_api_path is the API version, it defaults to the OpenAI path, which is not served by Gemini
+	version := DefaultGeminiAPIVersion
+	if p.config.CustomAPIPath != "" && p.config.CustomAPIPath != DefaultCustomAPIPath {
+		version = p.config.CustomAPIPath
+	}
+	url := p.config.BaseURL + version + "/models/" + p.config.Model
+	if p.config.Stream {
+		url += ":streamGenerateContent?alt=sse"
+	} else {
+		url += ":generateContent"
+	}
+
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		respChan <- StreamChunk{Error: fmt.Errorf("error creating request: %w", err)}
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	// Set headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Goog-Api-Key", p.config.APIKey)
+
+	p.logRequest(req, jsonBody)
+
+	return req, nil
+}
+
+func (p *GeminiProvider) CallStreamableChatCompletions(
+	ctx context.Context,
+	messages []*Message,
+	prompt *string,
+) (*Message, error) {
+	return p.BaseProvider.CallStreamableChatCompletions(
+		ctx, p.config.Provider, p.config.ReasoningEffort, messages, prompt, p.BuildRequest)
+}
+
+// CallStreamableChatCompletionsWithTools calls streamGenerateContent with the MCP tools as functionDeclarations
+func (p *GeminiProvider) CallStreamableChatCompletionsWithTools(
+	ctx context.Context,
+	messages []*Message,
+	prompt *string,
+	tools []*mcp.Tool,
+) (*Message, error) {
+	return p.BaseProvider.CallStreamableChatCompletions(
+		ctx, p.config.Provider, p.config.ReasoningEffort, messages, prompt,
+		func(ctx context.Context, respChan chan StreamChunk, messages []*Message, prompt *string) (*http.Request, error) {
+			return p.buildRequest(ctx, respChan, messages, prompt, tools)
+		})
+}
+
+// processResponse parses the SSE events of streamGenerateContent, or the only response of generateContent.
+// The stream has no done marker, it finishes with the finishReason of the candidate.
+func (p *GeminiProvider) processResponse(
+	ctx context.Context,
+	resp *http.Response,
+	respChan chan StreamChunk,
+) {
+	toolCallCount := 0
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			respChan <- StreamChunk{Error: fmt.Errorf("error reading response: %w", err)}
+			return
+		}
+
+		response := &GeminiResponse{}
+		if err := sonic.Unmarshal(data, response); err != nil {
+			respChan <- StreamChunk{Error: fmt.Errorf("error unmarshaling response: %w", err)}
+			return
+		}
+
+		chunk := geminiChunk(response, &toolCallCount)
+		chunk.Done = true
+		respChan <- chunk
+		return
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 16*bufio.MaxScanTokenSize)
+
+	lineCount := 0
+	for scanner.Scan() {
+		select {
+		case <-ctx.Done():
+			p.Info("Context cancelled")
+			respChan <- StreamChunk{Error: ctx.Err()}
+			return
+
+		default:
+		}
+
+		line := scanner.Text()
+		lineCount++
+		p.debugContent("Received line %d: %s", lineCount, line)
+
+		// NOTE: Ignore non-data lines
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		response := &GeminiResponse{}
+		if err := sonic.UnmarshalString(strings.TrimSpace(strings.TrimPrefix(line, "data:")), response); err != nil {
+			p.Errorf("Error unmarshaling response line: %v", err)
+			continue
+		}
+
+		chunk := geminiChunk(response, &toolCallCount)
+		respChan <- chunk
+		if chunk.Error != nil || chunk.Done {
+			p.Info("Stream marked as done")
+			return
+		}
+	}
+
+	p.Infof("Finished scanning response body, total lines: %d", lineCount)
+
+	if err := scanner.Err(); err != nil {
+		p.Errorf("Scanner error: %v", err)
+		respChan <- StreamChunk{Error: fmt.Errorf("error reading response stream: %w", err)}
+	}
+}
+
+// geminiChunk converts the first candidate of the response to a stream chunk.
+// NOTE: Gemini function calls usually have no ID, they are numbered in order like Ollama.
+func geminiChunk(response *GeminiResponse, toolCallCount *int) StreamChunk {
+	chunk := StreamChunk{ID: response.ResponseID, Model: response.ModelVersion}
+	if response.UsageMetadata != nil {
+		chunk.Usage = response.UsageMetadata.usage()
+	}
+
+	if len(response.Candidates) == 0 {
+		if response.PromptFeedback != nil && response.PromptFeedback.BlockReason != "" {
+			chunk.Error = fmt.Errorf("gemini blocked the prompt: %s", response.PromptFeedback.BlockReason)
+		}
+		return chunk
+	}
+
+	candidate := response.Candidates[0]
+	chunk.Done = candidate.FinishReason != ""
+	if candidate.Content == nil {
+		return chunk
+	}
+
+	for _, part := range candidate.Content.Parts {
+		if part.ThoughtSignature != "" {
+			chunk.ReasoningSignature = part.ThoughtSignature
+		}
+
+		switch {
+		case part.FunctionCall != nil:
+			args, err := sonic.MarshalString(part.FunctionCall.Args)
+			if err != nil {
+				continue
+			}
+
+			id := part.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("call_%d", *toolCallCount)
+			}
+			chunk.ToolCalls = append(chunk.ToolCalls, &ToolCallDelta{
+				Index:     *toolCallCount,
+				ID:        id,
+				Name:      part.FunctionCall.Name,
+				Arguments: args,
+			})
+			*toolCallCount++
+
+		case part.Thought:
+			chunk.ReasoningContent += part.Text
+
+		default:
+			chunk.Content += part.Text
+		}
+	}
+
+	return chunk
+}
+
+// geminiContents converts the messages to the Gemini contents. The native tool calls become
+// functionCall parts of the model, and the tool results become functionResponse parts of the user,
+// the results of the same turn are merged into one content.
+func geminiContents(messages []*Message) []*GeminiContent {
+	contents := make([]*GeminiContent, 0, len(messages))
+
+	for _, msg := range messages {
+		switch {
+		case msg.Role == RoleSystem:
+			continue
+
+		case msg.Role == RoleTool && msg.ToolCallID != "":
+			part := &GeminiPart{FunctionResponse: &GeminiFunctionResponse{
+				Name:     msg.Tool,
+				Response: map[string]any{"result": cast.ToString(msg.Content)},
+			}}
+
+			if len(contents) > 0 {
+				last := contents[len(contents)-1]
+				if last.Role == GeminiRoleUser && len(last.Parts) > 0 && last.Parts[0].FunctionResponse != nil {
+					last.Parts = append(last.Parts, part)
+					continue
+				}
+			}
+			contents = append(contents, &GeminiContent{Role: GeminiRoleUser, Parts: []*GeminiPart{part}})
+
+		case msg.Role == RoleAssistant:
+			contents = append(contents, &GeminiContent{Role: GeminiRoleModel, Parts: geminiModelParts(msg)})
+
+		default: // user, and the result of the XML tool use
+			contents = append(contents, &GeminiContent{Role: GeminiRoleUser, Parts: geminiTextParts(msg.Content)})
+		}
+	}
+
+	return contents
+}
+
+// geminiModelParts returns the text and functionCall parts of the assistant message.
+// NOTE: The thought signature is sent back on the first function call, or on the text without function calls.
+func geminiModelParts(msg *Message) []*GeminiPart {
+	parts := make([]*GeminiPart, 0, len(msg.ToolCalls)+1)
+	if len(msg.ToolCalls) == 0 || cast.ToString(msg.Content) != "" {
+		parts = append(parts, geminiTextParts(msg.Content)...)
+	}
+
+	for _, call := range msg.ToolCalls {
+		args := make(map[string]any)
+		_ = sonic.UnmarshalString(call.Function.Arguments, &args)
+
+		parts = append(parts, &GeminiPart{FunctionCall: &GeminiFunctionCall{Name: call.Function.Name, Args: args}})
+	}
+
+	if msg.Provider == ProviderGemini && msg.ReasoningSignature != "" && len(parts) > 0 {
+		idx := 0
+		for i, part := range parts {
+			if part.FunctionCall != nil {
+				idx = i
+				break
+			}
+		}
+		parts[idx].ThoughtSignature = msg.ReasoningSignature
+	}
+
+	return parts
+}
+
+// geminiTextParts converts the string or structured content of the message to text parts
+func geminiTextParts(content any) []*GeminiPart {
+	items, ok := content.([]map[string]any)
+	if !ok {
+		return []*GeminiPart{{Text: cast.ToString(content)}}
+	}
+
+	parts := make([]*GeminiPart, 0, len(items))
+	for _, item := range items {
+		parts = append(parts, &GeminiPart{Text: cast.ToString(item["text"])})
+	}
+
+	return parts
+}
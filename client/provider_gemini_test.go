@@ -0,0 +1,137 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bytedance/sonic"
+)
+
+// geminiStub is a stand-in of streamGenerateContent answering the n-th request with the n-th list of SSE events
+type geminiStub struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*GeminiRequest
+	urls     []string
+	headers  []http.Header
+}
+
+func newGeminiStub(t *testing.T, responses ...[]string) *geminiStub {
+	stub := &geminiStub{}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := &GeminiRequest{}
+		if err := sonic.Unmarshal(body, request); err != nil {
+			t.Errorf("failed to parse request: %v", err)
+		}
+
+		stub.mu.Lock()
+		idx := len(stub.requests)
+		stub.requests = append(stub.requests, request)
+		stub.urls = append(stub.urls, r.URL.String())
+		stub.headers = append(stub.headers, r.Header.Clone())
+		stub.mu.Unlock()
+
+		if idx >= len(responses) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range responses[idx] {
+			fmt.Fprintf(w, "data: %s\r\n\r\n", data)
+		}
+	}))
+	t.Cleanup(stub.Close)
+
+	return stub
+}
+
+func geminiTextEvent(text, finishReason string) string {
+	return fmt.Sprintf(`{"candidates":[{"content":{"role":"model","parts":[{"text":%q}]},"finishReason":%q}],`+
+		`"modelVersion":"test-model","responseId":"resp"}`, text, finishReason)
+}
+
+func TestManager_GeminiNativeFunctionCall(t *testing.T) {
+	stub := newGeminiStub(t,
+		[]string{
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"Use echo.","thought":true}]}}],` +
+				`"modelVersion":"test-model","responseId":"resp-1"}`,
+			`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"echo","args":{"text":"hi"}},` +
+				`"thoughtSignature":"sig"}]},"finishReason":"STOP"}],"modelVersion":"test-model","responseId":"resp-1"}`,
+		},
+		[]string{geminiTextEvent("The tool said ", ""), geminiTextEvent("echo: hi", "STOP")},
+	)
+	mgr := newTestManager(t, ProviderGemini, stub.URL, ToolCallModeNative)
+
+	msg, err := mgr.HandleUserTextInput(t.Context(), "echo hi")
+	if err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+	if msg.Content != "The tool said echo: hi" {
+		t.Errorf("final message = %q, want the text of the second response", msg.Content)
+	}
+
+	if len(stub.requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(stub.requests))
+	}
+	if want := "/v1beta/models/test-model:streamGenerateContent?alt=sse"; stub.urls[0] != want {
+		t.Errorf("request url = %s, want %s", stub.urls[0], want)
+	}
+	if _, ok := stub.headers[0]["X-Goog-Api-Key"]; !ok {
+		t.Error("x-goog-api-key header is missing")
+	}
+
+	first := stub.requests[0]
+	if first.SystemInstruction == nil || len(first.SystemInstruction.Parts) != 1 {
+		t.Errorf("systemInstruction = %+v, want the system prompt", first.SystemInstruction)
+	}
+	if len(first.Contents) != 1 || first.Contents[0].Role != GeminiRoleUser ||
+		first.Contents[0].Parts[0].Text != "echo hi" {
+		t.Errorf("contents = %+v, want only the user message", first.Contents)
+	}
+	if len(first.Tools) != 1 || len(first.Tools[0].FunctionDeclarations) != 2 ||
+		first.Tools[0].FunctionDeclarations[0].Name != "echo" ||
+		first.Tools[0].FunctionDeclarations[0].ParametersJSONSchema == nil {
+		t.Errorf("tools = %+v, want the function declarations of the test server", first.Tools)
+	}
+
+	// NOTE: The function call is sent back with its thought signature, followed by its response
+	second, err := sonic.MarshalString(stub.requests[1].Contents)
+	if err != nil {
+		t.Fatalf("failed to marshal contents: %v", err)
+	}
+	for _, want := range []string{
+		`{"role":"model","parts":[{"thoughtSignature":"sig","functionCall":{"name":"echo","args":{"text":"hi"}}}]}`,
+		`{"role":"user","parts":[{"functionResponse":{"name":"echo","response":{"result":"echo: hi"}}}]}`,
+	} {
+		if !strings.Contains(second, want) {
+			t.Errorf("second request contents = %s, want %s", second, want)
+		}
+	}
+}
+
+func TestGeminiProvider_BlockedPrompt(t *testing.T) {
+	stub := newGeminiStub(t, []string{`{"promptFeedback":{"blockReason":"SAFETY"},"responseId":"resp"}`})
+	provider := NewGeminiProvider(&Config{
+		Provider:      ProviderGemini,
+		BaseURL:       stub.URL,
+		CustomAPIPath: "/v1",
+		Model:         "test-model",
+		Stream:        true,
+	}, &discardLogger{})
+
+	_, err := provider.CallStreamableChatCompletions(t.Context(), []*Message{NewMessageWithOption(RoleUser, "hi", nil)}, nil)
+	if err == nil || !strings.Contains(err.Error(), "SAFETY") {
+		t.Errorf("CallStreamableChatCompletions() error = %v, want the block reason", err)
+	}
+	if want := "/v1/models/test-model:streamGenerateContent?alt=sse"; stub.urls[0] != want {
+		t.Errorf("request url = %s, want %s", stub.urls[0], want)
+	}
+}
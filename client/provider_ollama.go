@@ -1,29 +1,34 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/kydenul/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/samber/lo"
 )
 
 var (
-	_ Provider = (*OllamaFormatProvider)(nil)
+	_ Provider         = (*OllamaFormatProvider)(nil)
+	_ NativeToolCaller = (*OllamaFormatProvider)(nil)
 
 	ToolTags = []string{"use_mcp_tool", "access_mcp_resource"}
 )
 
 // OllamaStreamResponse 是用于解码 Ollama /api/chat 流式响应中每一个 JSON 对象的结构体
 type OllamaStreamResponse struct {
-	Model     string    `json:"model"`      // 本次请求所使用的模型
-	CreatedAt time.Time `json:"created_at"` // 响应创建的 UTC 时间戳 2025-08-28T03:42:30.559748Z
-	Message   Message   `json:"message"`    // 包含模型生成内容的对象
-	Done      bool      `json:"done"`       // 用于指示生成过程是否已完成
+	Model     string        `json:"model"`      // 本次请求所使用的模型
+	CreatedAt time.Time     `json:"created_at"` // 响应创建的 UTC 时间戳 2025-08-28T03:42:30.559748Z
+	Message   OllamaMessage `json:"message"`    // 包含模型生成内容的对象
+	Done      bool          `json:"done"`       // 用于指示生成过程是否已完成
+	Error     string        `json:"error"`      // 请求失败时的错误信息
 
 	// --->>> 以下字段: 仅在最后一个响应中出现 <<<---
 
@@ -36,11 +41,45 @@ type OllamaStreamResponse struct {
 	EvalDuration       int64  `json:"eval_duration"`        // 生成所有回答 token 所花费的总时间(ns), 模型“思考并写出答案”所用的时间
 }
 
+// usage returns the token usage and the timings of the last response
+func (r *OllamaStreamResponse) usage() *Usage {
+	return &Usage{
+		PromptTokens:     r.PromptEvalCount,
+		CompletionTokens: r.EvalCount,
+
+		TotalDuration:      time.Duration(r.TotalDuration),
+		LoadDuration:       time.Duration(r.LoadDuration),
+		PromptEvalDuration: time.Duration(r.PromptEvalDuration),
+		EvalDuration:       time.Duration(r.EvalDuration),
+	}
+}
+
+// OllamaMessage 是 Ollama /api/chat 响应中的消息
+type OllamaMessage struct {
+	Role      string            `json:"role"`
+	Content   string            `json:"content"`
+	Thinking  string            `json:"thinking,omitempty"`   // 推理模型的推理内容
+	ToolCalls []*OllamaToolCall `json:"tool_calls,omitempty"` // 模型请求的工具调用，每个调用都是完整的
+}
+
+// OllamaToolCall 是 Ollama 的工具调用，没有 ID，arguments 是 JSON 对象而不是字符串
+type OllamaToolCall struct {
+	Function OllamaToolCallFunction `json:"function"`
+}
+
+// OllamaToolCallFunction 是 Ollama 工具调用的函数名和参数
+type OllamaToolCallFunction struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
+
 // OllamaChatRequest 是 Ollama API 的请求结构体
 type OllamaChatRequest struct {
 	Model    string           `json:"model"`
 	Messages []map[string]any `json:"messages"`
 	Stream   bool             `json:"stream"`
+
+	Tools []*OpenAITool `json:"tools,omitempty"` // 与 OpenAI 的 tools 格式相同
 }
 
 type OllamaFormatProvider struct {
@@ -50,13 +89,20 @@ type OllamaFormatProvider struct {
 }
 
 func NewOllmaFormatProvider(config *Config, logger log.Logger) *OllamaFormatProvider {
-	return &OllamaFormatProvider{
+	p := &OllamaFormatProvider{
 		BaseProvider: BaseProvider{
 			Logger: logger,
-			Client: &http.Client{Timeout: DefaultTimeout},
+			Client: NewHTTPClient(),
+			Retry:  NewRetryPolicy(config),
+
+			Redactor:  NewRedactor(config),
+			DebugHTTP: config.DebugHTTP,
 		},
 		config: config,
 	}
+	p.ProcessResponse = p.processResponse
+
+	return p
 }
 
 func (p *OllamaFormatProvider) BuildRequest(
@@ -64,6 +110,16 @@ func (p *OllamaFormatProvider) BuildRequest(
 	respChan chan StreamChunk,
 	messages []*Message,
 	systemPrompt *string,
+) (*http.Request, error) {
+	return p.buildRequest(ctx, respChan, messages, systemPrompt, nil)
+}
+
+func (p *OllamaFormatProvider) buildRequest(
+	ctx context.Context,
+	respChan chan StreamChunk,
+	messages []*Message,
+	systemPrompt *string,
+	tools []*mcp.Tool,
 ) (*http.Request, error) {
 	p.Infof("Starting Ollama stream request")
 	// Prepare messages for completion
@@ -73,12 +129,19 @@ func (p *OllamaFormatProvider) BuildRequest(
 	body := OllamaChatRequest{
 		Model: p.config.Model,
 		Messages: lo.Map(preparedMessages, func(message *Message, _ int) map[string]any {
-			return map[string]any{
-				"role":    message.Role,
-				"content": message.Content,
-			}
+			return ollamaMessage(message)
 		}),
 		Stream: p.config.Stream,
+		Tools: lo.Map(tools, func(tool *mcp.Tool, _ int) *OpenAITool {
+			return &OpenAITool{
+				Type: ToolCallTypeFunction,
+				Function: OpenAIToolFunction{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  tool.InputSchema,
+				},
+			}
+		}),
 	}
 
 	jsonBody, err := sonic.Marshal(body)
@@ -96,8 +159,6 @@ func (p *OllamaFormatProvider) BuildRequest(
 		url += DefaultCustomAPIPath // Ollama 使用 /chat 端点，base-url 已经包含 /api
 	}
 
-	p.Infof("Making request to URL: %s. Request body: %s", url, string(jsonBody))
-
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
 	if err != nil {
@@ -107,14 +168,139 @@ func (p *OllamaFormatProvider) BuildRequest(
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
+	p.logRequest(req, jsonBody)
 
 	return req, nil
 }
 
 func (p *OllamaFormatProvider) CallStreamableChatCompletions(
+	ctx context.Context,
 	messages []*Message,
 	prompt *string,
-) *Message {
+) (*Message, error) {
 	return p.BaseProvider.CallStreamableChatCompletions(
-		p.config.Provider, p.config.ReasoningEffort, messages, prompt, p.BuildRequest)
+		ctx, p.config.Provider, p.config.ReasoningEffort, messages, prompt, p.BuildRequest)
+}
+
+// CallStreamableChatCompletionsWithTools calls /api/chat with the MCP tools in the tools field
+func (p *OllamaFormatProvider) CallStreamableChatCompletionsWithTools(
+	ctx context.Context,
+	messages []*Message,
+	prompt *string,
+	tools []*mcp.Tool,
+) (*Message, error) {
+	return p.BaseProvider.CallStreamableChatCompletions(
+		ctx, p.config.Provider, p.config.ReasoningEffort, messages, prompt,
+		func(ctx context.Context, respChan chan StreamChunk, messages []*Message, prompt *string) (*http.Request, error) {
+			return p.buildRequest(ctx, respChan, messages, prompt, tools)
+		})
+}
+
+// processResponse parses the response of /api/chat, which is one JSON object per line
+// instead of SSE, and the only object if stream is false
+func (p *OllamaFormatProvider) processResponse(
+	ctx context.Context,
+	resp *http.Response,
+	respChan chan StreamChunk,
+) {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 16*bufio.MaxScanTokenSize)
+
+	lineCount, toolCallCount := 0, 0
+	for scanner.Scan() {
+		select {
+		case <-ctx.Done():
+			p.Info("Context cancelled")
+			respChan <- StreamChunk{Error: ctx.Err()}
+			return
+
+		default:
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		lineCount++
+		p.debugContent("Received line %d: %s", lineCount, line)
+		if line == "" {
+			continue
+		}
+
+		response := &OllamaStreamResponse{}
+		if err := sonic.UnmarshalString(line, response); err != nil {
+			p.Errorf("Error unmarshaling response line: %v", err)
+			continue
+		}
+
+		if response.Error != "" {
+			p.Errorf("Ollama error: %s", response.Error)
+			respChan <- StreamChunk{Error: fmt.Errorf("ollama error: %s", response.Error)}
+			return
+		}
+
+		// NOTE: Ollama 的工具调用没有 ID 且一次性返回，按出现顺序编号
+		toolCalls := make([]*ToolCallDelta, 0, len(response.Message.ToolCalls))
+		for _, call := range response.Message.ToolCalls {
+			args, err := sonic.MarshalString(call.Function.Arguments)
+			if err != nil {
+				p.Errorf("Error marshaling tool call arguments: %v", err)
+				continue
+			}
+
+			toolCalls = append(toolCalls, &ToolCallDelta{
+				Index:     toolCallCount,
+				ID:        fmt.Sprintf("call_%d", toolCallCount),
+				Name:      call.Function.Name,
+				Arguments: args,
+			})
+			toolCallCount++
+		}
+
+		chunk := StreamChunk{
+			Model: response.Model,
+
+			Content:          response.Message.Content,
+			ReasoningContent: response.Message.Thinking,
+			ToolCalls:        toolCalls,
+			Done:             response.Done,
+		}
+		if response.Done {
+			chunk.Usage = response.usage()
+		}
+		respChan <- chunk
+
+		if response.Done {
+			p.Infof("Stream marked as done: %s", response.DoneReason)
+			break
+		}
+	}
+
+	p.Infof("Finished scanning response body, total lines: %d", lineCount)
+
+	if err := scanner.Err(); err != nil {
+		p.Errorf("Scanner error: %v", err)
+		respChan <- StreamChunk{Error: fmt.Errorf("error reading response stream: %w", err)}
+	}
+}
+
+// ollamaMessage converts the message to the Ollama format, whose tool call arguments are JSON objects
+func ollamaMessage(message *Message) map[string]any {
+	msg := map[string]any{
+		"role":    message.Role,
+		"content": message.Content,
+	}
+
+	if len(message.ToolCalls) > 0 {
+		msg["tool_calls"] = lo.Map(message.ToolCalls, func(call *ToolCall, _ int) *OllamaToolCall {
+			args := make(map[string]any)
+			_ = sonic.UnmarshalString(call.Function.Arguments, &args)
+
+			return &OllamaToolCall{
+				Function: OllamaToolCallFunction{Name: call.Function.Name, Arguments: args},
+			}
+		})
+	}
+	if message.Role == RoleTool && message.ToolCallID != "" {
+		msg["tool_name"] = message.Tool
+	}
+
+	return msg
 }
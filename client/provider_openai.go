@@ -9,27 +9,107 @@ import (
 
 	"github.com/bytedance/sonic"
 	"github.com/kydenul/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/samber/lo"
 )
 
+var (
+	_ Provider         = (*OpenAIFormatProvider)(nil)
+	_ NativeToolCaller = (*OpenAIFormatProvider)(nil)
+)
+
 // OpenAIChatRequest 是用于发送 OpenAI /v1/chat/completions 请求的结构体
 type OpenAIChatRequest struct {
 	Model    string           `json:"model"`
 	Messages []map[string]any `json:"messages"`
 	Stream   bool             `json:"stream"`
 
+	StreamOptions *OpenAIStreamOptions `json:"stream_options,omitempty"` // 流式响应的最后一个数据块返回 usage
+
 	IncludeReasoning bool `json:"include_reasoning,omitempty"` // deepseek-r1
 	Thinking         bool `json:"thinking,omitempty"`          // deepseekv3.1
 
 	ReasoningEffort string `json:"reasoning_effort,omitempty"`
 	MaxTokens       uint64 `json:"max_tokens,omitempty"`
+
+	Tools []*OpenAITool `json:"tools,omitempty"` // Native function calling
+}
+
+// OpenAIStreamOptions 是流式请求的选项
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// OpenAITool 是请求中 tools 数组的元素，描述一个可供模型调用的函数
+type OpenAITool struct {
+	Type     string             `json:"type"` // Always "function"
+	Function OpenAIToolFunction `json:"function"`
+}
+
+// OpenAIToolFunction 描述函数的名称、用途以及 JSON Schema 格式的参数
+type OpenAIToolFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
 }
 
 // OpenAIStreamChoiceDelta 代表 OpenAI 流中的增量变化
 type OpenAIStreamChoiceDelta struct {
 	Content          string `json:"content"`
 	ReasoningContent string `json:"reasoning_content"` // DeepSeek-R1 模型的推理内容
+	Reasoning        string `json:"reasoning"`         // OpenRouter 的推理内容
 	Role             string `json:"role"`              // 通常只在第一个数据块出现
+
+	ToolCalls []*OpenAIToolCallDelta `json:"tool_calls,omitempty"` // 工具调用的片段
+}
+
+// OpenAIToolCallDelta 是流式返回的工具调用片段，id 和 name 只在每个调用的第一个片段出现，
+// arguments 需要按 index 拼接
+type OpenAIToolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+// content returns the answer content of the delta
+func (d *OpenAIStreamChoiceDelta) content() string {
+	if d == nil {
+		return ""
+	}
+
+	return d.Content
+}
+
+// reasoning returns the reasoning content of the delta, which is named differently by the providers
+func (d *OpenAIStreamChoiceDelta) reasoning() string {
+	if d == nil {
+		return ""
+	}
+	if d.ReasoningContent != "" {
+		return d.ReasoningContent
+	}
+
+	return d.Reasoning
+}
+
+// toolCallDeltas converts the tool call fragments of the delta
+func (d *OpenAIStreamChoiceDelta) toolCallDeltas() []*ToolCallDelta {
+	if d == nil || len(d.ToolCalls) == 0 {
+		return nil
+	}
+
+	return lo.Map(d.ToolCalls, func(call *OpenAIToolCallDelta, _ int) *ToolCallDelta {
+		return &ToolCallDelta{
+			Index:     call.Index,
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		}
+	})
 }
 
 // OpenAIStreamChoice 代表 OpenAI 流中的一个选项
@@ -47,6 +127,26 @@ type OpenAIStreamResponse struct {
 	Model             string                `json:"model"`
 	SystemFingerprint string                `json:"system_fingerprint"`
 	Choices           []*OpenAIStreamChoice `json:"choices"`
+	Usage             *OpenAIUsage          `json:"usage,omitempty"` // 在最后一个数据块出现，此时 choices 为空
+}
+
+// OpenAIUsage 是响应的 token 用量
+type OpenAIUsage struct {
+	PromptTokens            int `json:"prompt_tokens"`
+	CompletionTokens        int `json:"completion_tokens"`
+	CompletionTokensDetails *struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details,omitempty"`
+}
+
+// usage converts the token usage of the response
+func (u *OpenAIUsage) usage() *Usage {
+	usage := &Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
+	if u.CompletionTokensDetails != nil {
+		usage.ReasoningTokens = u.CompletionTokensDetails.ReasoningTokens
+	}
+
+	return usage
 }
 
 type OpenAIFormatProvider struct {
@@ -60,7 +160,11 @@ func NewOpenAIFormatProvider(config *Config, logger log.Logger) *OpenAIFormatPro
 		BaseProvider: BaseProvider{
 			Logger: logger,
 
-			Client: &http.Client{Timeout: DefaultTimeout},
+			Client: NewHTTPClient(),
+			Retry:  NewRetryPolicy(config),
+
+			Redactor:  NewRedactor(config),
+			DebugHTTP: config.DebugHTTP,
 		},
 		config: config,
 	}
@@ -71,6 +175,16 @@ func (p *OpenAIFormatProvider) BuildRequest(
 	respChan chan StreamChunk,
 	messages []*Message,
 	systemPrompt *string,
+) (*http.Request, error) {
+	return p.buildRequest(ctx, respChan, messages, systemPrompt, nil)
+}
+
+func (p *OpenAIFormatProvider) buildRequest(
+	ctx context.Context,
+	respChan chan StreamChunk,
+	messages []*Message,
+	systemPrompt *string,
+	tools []*mcp.Tool,
 ) (*http.Request, error) {
 	p.Infof("Starting OpenAI stream request")
 	// Prepare messages for completion
@@ -80,12 +194,22 @@ func (p *OpenAIFormatProvider) BuildRequest(
 	body := OpenAIChatRequest{
 		Model: p.config.Model,
 		Messages: lo.Map(preparedMessages, func(message *Message, _ int) map[string]any {
-			return map[string]any{
-				"role":    message.Role,
-				"content": message.Content,
-			}
+			return openAIMessage(message)
 		}),
 		Stream: p.config.Stream,
+		Tools: lo.Map(tools, func(tool *mcp.Tool, _ int) *OpenAITool {
+			return &OpenAITool{
+				Type: ToolCallTypeFunction,
+				Function: OpenAIToolFunction{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  tool.InputSchema,
+				},
+			}
+		}),
+	}
+	if body.Stream {
+		body.StreamOptions = &OpenAIStreamOptions{IncludeUsage: true}
 	}
 	body.IncludeReasoning = strings.Contains(p.config.Model, ModelDeepSeekR1)
 	if p.config.MaxTokens > 0 {
@@ -129,17 +253,48 @@ func (p *OpenAIFormatProvider) BuildRequest(
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
 
-	// XXX: 调用辅助函数生成 curl 命令字符串
-	curlCmd, _ := p.GenerateCurlCommand(req, jsonBody)
-	p.Infof("--- Replayable curl command ---\n%s\n-----------------------------", curlCmd)
+	p.logRequest(req, jsonBody)
 
 	return req, nil
 }
 
 func (p *OpenAIFormatProvider) CallStreamableChatCompletions(
+	ctx context.Context,
+	messages []*Message,
+	prompt *string,
+) (*Message, error) {
+	return p.BaseProvider.CallStreamableChatCompletions(
+		ctx, p.config.Provider, p.config.ReasoningEffort, messages, prompt, p.BuildRequest)
+}
+
+// CallStreamableChatCompletionsWithTools calls the chat completions with the MCP tools in the tools array
+func (p *OpenAIFormatProvider) CallStreamableChatCompletionsWithTools(
+	ctx context.Context,
 	messages []*Message,
 	prompt *string,
-) *Message {
+	tools []*mcp.Tool,
+) (*Message, error) {
 	return p.BaseProvider.CallStreamableChatCompletions(
-		p.config.Provider, p.config.ReasoningEffort, messages, prompt, p.BuildRequest)
+		ctx, p.config.Provider, p.config.ReasoningEffort, messages, prompt,
+		func(ctx context.Context, respChan chan StreamChunk, messages []*Message, prompt *string) (*http.Request, error) {
+			return p.buildRequest(ctx, respChan, messages, prompt, tools)
+		})
+}
+
+// openAIMessage converts the message to the OpenAI format, with the native tool calls
+// of the assistant and the ID of the tool call answered by a tool message
+func openAIMessage(message *Message) map[string]any {
+	msg := map[string]any{
+		"role":    message.Role,
+		"content": message.Content,
+	}
+
+	if len(message.ToolCalls) > 0 {
+		msg["tool_calls"] = message.ToolCalls
+	}
+	if message.ToolCallID != "" {
+		msg["tool_call_id"] = message.ToolCallID
+	}
+
+	return msg
 }
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kydenul/log"
+)
+
+// ErrUnknownProvider is returned when Config.Provider is not a registered provider
+var ErrUnknownProvider = errors.New("unknown provider")
+
+// ProviderFactory creates the provider with the config
+type ProviderFactory func(config *Config, logger log.Logger) Provider
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]ProviderFactory)
+)
+
+func init() {
+	RegisterProvider(ProviderOpenAI, func(config *Config, logger log.Logger) Provider {
+		return NewOpenAIFormatProvider(config, logger)
+	})
+	RegisterProvider(ProviderOllama, func(config *Config, logger log.Logger) Provider {
+		return NewOllmaFormatProvider(config, logger)
+	})
+	RegisterProvider(ProviderTaiji, func(config *Config, logger log.Logger) Provider {
+		return NewTaijiProvider(config, logger)
+	})
+	RegisterProvider(ProviderAnthropic, func(config *Config, logger log.Logger) Provider {
+		return NewAnthropicProvider(config, logger)
+	})
+	RegisterProvider(ProviderGemini, func(config *Config, logger log.Logger) Provider {
+		return NewGeminiProvider(config, logger)
+	})
+}
+
+// RegisterProvider makes the provider available by name in the `provider` field of client.yaml,
+// replacing the provider registered with the same name, including the builtin ones.
+// It panics if the name is empty or the factory is nil.
+func RegisterProvider(name string, factory func(*Config, log.Logger) Provider) {
+	if name == "" || factory == nil {
+		panic("client: RegisterProvider name or factory is empty")
+	}
+
+	providersMu.Lock()
+	providers[name] = factory
+	providersMu.Unlock()
+}
+
+// Providers returns the sorted names of the registered providers
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NewProvider creates the provider registered as config.Provider
+func NewProvider(config *Config, logger log.Logger) (Provider, error) {
+	providersMu.RLock()
+	factory, ok := providers[config.Provider]
+	providersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w '%s', the registered providers are: %s",
+			ErrUnknownProvider, config.Provider, strings.Join(Providers(), ", "))
+	}
+
+	config, err := config.ResolveAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return factory(config, logger), nil
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kydenul/log"
+)
+
+// gatewayProvider is a provider registered out of the builtin ones, answering with a fixed content
+type gatewayProvider struct {
+	config *Config
+}
+
+func (p *gatewayProvider) CallStreamableChatCompletions(
+	_ context.Context, _ []*Message, _ *string,
+) (*Message, error) {
+	return NewMessageWithOption(RoleAssistant, "hello from "+p.config.Model, &MessageOption{
+		Provider: p.config.Provider,
+		Model:    p.config.Model,
+	}), nil
+}
+
+func (*gatewayProvider) BuildRequest(
+	_ context.Context, _ chan StreamChunk, _ []*Message, _ *string,
+) (*http.Request, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestRegisterProvider(t *testing.T) {
+	RegisterProvider("TestGateway", func(config *Config, _ log.Logger) Provider {
+		return &gatewayProvider{config: config}
+	})
+
+	for _, name := range []string{
+		ProviderOpenAI, ProviderOllama, ProviderTaiji, ProviderAnthropic, ProviderGemini, "TestGateway",
+	} {
+		if !slices.Contains(Providers(), name) {
+			t.Errorf("Providers() = %v, want %s registered", Providers(), name)
+		}
+	}
+
+	mgr := newTestManager(t, "TestGateway", "", ToolCallModeNative)
+	msg, err := mgr.HandleUserTextInput(t.Context(), "hi")
+	if err != nil {
+		t.Fatalf("HandleUserTextInput() error = %v", err)
+	}
+	if msg.Content != "hello from test-model" {
+		t.Errorf("content = %q, want the answer of the registered provider", msg.Content)
+	}
+}
+
+func TestNewProvider_Unknown(t *testing.T) {
+	_, err := NewProvider(&Config{Provider: "OpenAl"}, &discardLogger{})
+	if !errors.Is(err, ErrUnknownProvider) {
+		t.Fatalf("NewProvider() error = %v, want ErrUnknownProvider", err)
+	}
+	if !strings.Contains(err.Error(), "'OpenAl'") || !strings.Contains(err.Error(), ProviderOpenAI) {
+		t.Errorf("NewProvider() error = %v, want the name and the registered providers", err)
+	}
+}
+
+func TestManager_StreamUserTextInputWithoutDeltas(t *testing.T) {
+	RegisterProvider("TestGateway", func(config *Config, _ log.Logger) Provider {
+		return &gatewayProvider{config: config}
+	})
+	mgr := newTestManager(t, "TestGateway", "", ToolCallModeNative)
+
+	// NOTE: The answer of a provider without text deltas is emitted as one delta
+	var deltas []string
+	for event := range mgr.StreamUserTextInput(t.Context(), "hi") {
+		if event.Type == EventTextDelta {
+			deltas = append(deltas, event.Delta)
+		}
+	}
+	if !slices.Equal(deltas, []string{"hello from test-model"}) {
+		t.Errorf("text deltas = %q, want the whole answer", deltas)
+	}
+}
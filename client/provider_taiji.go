@@ -36,7 +36,11 @@ func NewTaijiProvider(config *Config, logger log.Logger) *TaijiProvider {
 		BaseProvider: BaseProvider{
 			Logger: logger,
 
-			Client: &http.Client{Timeout: DefaultTimeout},
+			Client: NewHTTPClient(),
+			Retry:  NewRetryPolicy(config),
+
+			Redactor:  NewRedactor(config),
+			DebugHTTP: config.DebugHTTP,
 		},
 		config: config,
 	}
@@ -103,16 +107,16 @@ func (p *TaijiProvider) BuildRequest(
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
 
-	curlCmd, _ := p.GenerateCurlCommand(req, jsonBody)
-	p.Infof("--- Replayable curl command ---\n%s\n-----------------------------", curlCmd)
+	p.logRequest(req, jsonBody)
 
 	return req, nil
 }
 
 func (p *TaijiProvider) CallStreamableChatCompletions(
+	ctx context.Context,
 	messages []*Message,
 	prompt *string,
-) *Message {
+) (*Message, error) {
 	return p.BaseProvider.CallStreamableChatCompletions(
-		p.config.Provider, p.config.ReasoningEffort, messages, prompt, p.BuildRequest)
+		ctx, p.config.Provider, p.config.ReasoningEffort, messages, prompt, p.BuildRequest)
 }
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/bytedance/sonic"
+)
+
+// Redacted replaces the secrets in the logs
+const Redacted = "***"
+
+var (
+	// SensitiveHeaders are the request headers whose value is always redacted
+	SensitiveHeaders = []string{
+		"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "X-Goog-Api-Key",
+	}
+
+	// DefaultSensitiveFields are the JSON fields and URL query parameters always redacted,
+	// more can be configured with sensitive_fields in client.yaml
+	DefaultSensitiveFields = []string{
+		"api_key", "apikey", "key", "token", "access_token", "refresh_token", "password", "secret",
+	}
+)
+
+// Redactor masks the auth headers, the API keys and the sensitive fields of the requests before they are logged
+type Redactor struct {
+	fields  []string // 小写的敏感字段名
+	secrets []string // 配置中的 API key，出现在任何位置都会被替换
+}
+
+// NewRedactor returns the redactor of the sensitive_fields and the API keys of config
+func NewRedactor(config *Config) *Redactor {
+	redactor := &Redactor{fields: slices.Clone(DefaultSensitiveFields)}
+	if config == nil {
+		return redactor
+	}
+
+	root := config
+	if config.root != nil {
+		root = config.root
+	}
+	for _, field := range root.SensitiveFields {
+		redactor.fields = append(redactor.fields, strings.ToLower(field))
+	}
+
+	secrets := []string{config.APIKey, root.APIKey}
+	for _, profile := range root.Profiles {
+		if profile != nil {
+			secrets = append(secrets, profile.APIKey)
+		}
+	}
+	for _, fallback := range root.Fallbacks {
+		if fallback != nil {
+			secrets = append(secrets, fallback.APIKey)
+		}
+	}
+	for _, secret := range secrets {
+		if secret != "" && !slices.Contains(redactor.secrets, secret) {
+			redactor.secrets = append(redactor.secrets, secret)
+		}
+	}
+
+	return redactor
+}
+
+// Header returns the value of the header, masked if the header is sensitive.
+// The auth scheme is kept, e.g. `Bearer ***`.
+func (r *Redactor) Header(key, value string) string {
+	if !slices.ContainsFunc(SensitiveHeaders, func(header string) bool { return strings.EqualFold(header, key) }) {
+		return r.String(value)
+	}
+
+	if scheme, _, found := strings.Cut(value, " "); found && !strings.Contains(scheme, ":") {
+		return scheme + " " + Redacted
+	}
+
+	return Redacted
+}
+
+// URL returns the URL with the sensitive query parameters masked, e.g. the key of Gemini
+func (r *Redactor) URL(u *url.URL) string {
+	masked := *u
+	query := masked.Query()
+	for name := range query {
+		if r.isSensitive(name) {
+			query.Set(name, Redacted)
+		}
+	}
+	masked.RawQuery = query.Encode()
+	masked.User = nil
+
+	return r.String(masked.String())
+}
+
+// Body returns the JSON body with the sensitive fields masked, the body is kept as is if not JSON
+func (r *Redactor) Body(body []byte) string {
+	var value any
+	if err := sonic.Unmarshal(body, &value); err != nil {
+		return r.String(string(body))
+	}
+
+	masked, err := sonic.MarshalString(r.mask(value))
+	if err != nil {
+		return r.String(string(body))
+	}
+
+	return r.String(masked)
+}
+
+// String returns s with the API keys masked
+func (r *Redactor) String(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+
+	return s
+}
+
+// mask masks the sensitive fields of the decoded JSON value recursively
+func (r *Redactor) mask(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if _, isString := field.(string); isString && r.isSensitive(key) {
+				value[key] = Redacted
+				continue
+			}
+			value[key] = r.mask(field)
+		}
+	case []any:
+		for idx, item := range value {
+			value[idx] = r.mask(item)
+		}
+	}
+
+	return value
+}
+
+func (r *Redactor) isSensitive(name string) bool {
+	return slices.Contains(r.fields, strings.ToLower(name))
+}
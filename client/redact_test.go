@@ -0,0 +1,69 @@
+package client
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBaseProvider_GenerateCurlCommand(t *testing.T) {
+	config := &Config{
+		APIKey:          "sk-primary",
+		Fallbacks:       []*FallbackConfig{{APIKey: "sk-fallback"}},
+		SensitiveFields: []string{"user_secret"},
+	}
+	body := []byte(`{"model":"m","messages":[{"role":"user","content":"hello sk-fallback"}],` +
+		`"api_key":"sk-in-body","metadata":{"user_secret":"s3cr3t","max_tokens":1}}`)
+
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/v1?key=sk-query&alt=sse", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer sk-primary")
+	req.Header.Set("X-Api-Key", "sk-primary")
+	req.Header.Set("X-Goog-Api-Key", "sk-primary")
+	req.Header.Set("X-Title", "K-CLI")
+
+	tests := []struct {
+		name      string
+		debugHTTP bool
+		want      []string
+		notWant   []string
+	}{
+		{
+			name: "body omitted",
+			want: []string{
+				"Authorization: Bearer ***", "X-Api-Key: ***", "X-Goog-Api-Key: ***", "X-Title: K-CLI", "key=%2A%2A%2A",
+			},
+			notWant: []string{"sk-", "hello", "s3cr3t"},
+		},
+		{
+			name:      "debug_http",
+			debugHTTP: true,
+			want:      []string{"Authorization: Bearer ***", "hello ***", `\"user_secret\":\"***\"`, `\"max_tokens\":1`},
+			notWant:   []string{"sk-", "s3cr3t"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &BaseProvider{Redactor: NewRedactor(config), DebugHTTP: tt.debugHTTP}
+
+			curlCmd, err := p.GenerateCurlCommand(req, body)
+			if err != nil {
+				t.Fatalf("GenerateCurlCommand() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(curlCmd, want) {
+					t.Errorf("GenerateCurlCommand() does not contain %q:\n%s", want, curlCmd)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(curlCmd, notWant) {
+					t.Errorf("GenerateCurlCommand() contains %q:\n%s", notWant, curlCmd)
+				}
+			}
+		})
+	}
+}
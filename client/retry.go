@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultMaxRetries     = 3
+	DefaultRetryBaseDelay = time.Second
+	DefaultMaxRetryDelay  = 30 * time.Second
+
+	// MaxErrorBodySize is the maximum number of bytes of the error response kept in HTTPError
+	MaxErrorBodySize = 4096
+)
+
+// HTTPError is returned when the provider answers with a non-200 status,
+// Body holds the error returned by the provider, usually JSON
+type HTTPError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // Retry-After 响应头，没有时为 0
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("HTTP error: status code %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("HTTP error: status code %d: %s", e.StatusCode, e.Body)
+}
+
+// newHTTPError reads the error of the response, at most MaxErrorBodySize bytes
+func newHTTPError(resp *http.Response) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, MaxErrorBodySize))
+	retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+		RetryAfter: retryAfter,
+	}
+}
+
+// RetryPolicy retries the requests failed with 408, 429, 5xx or a connection reset
+// with exponential backoff and jitter. Only the request is retried, never a broken stream.
+type RetryPolicy struct {
+	MaxRetries int           // 最大重试次数，0 表示不重试
+	BaseDelay  time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxDelay   time.Duration // 最长等待时间，Retry-After 超过该值时只等待 MaxDelay
+}
+
+// NewRetryPolicy returns the retry policy of max_retries and retry_base_delay in config
+func NewRetryPolicy(config *Config) RetryPolicy {
+	policy := RetryPolicy{
+		MaxRetries: int(config.MaxRetries),
+		BaseDelay:  config.RetryBaseDelay,
+		MaxDelay:   DefaultMaxRetryDelay,
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = DefaultRetryBaseDelay
+	}
+
+	return policy
+}
+
+// delay returns the time to wait before the retry after the attempt (0 based) failed with err,
+// false if the error is not retryable or the retries are exhausted
+func (p RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.MaxRetries || !isRetryable(err) {
+		return 0, false
+	}
+
+	// NOTE: Equal jitter, half of the backoff is random
+	backoff := p.MaxDelay
+	if attempt < 16 {
+		backoff = min(p.BaseDelay<<attempt, p.MaxDelay)
+	}
+	delay := backoff/2 + rand.N(backoff/2+1)
+
+	// NOTE: Retry-After is honoured, a longer wait than MaxDelay is capped to MaxDelay
+	if httpErr := (*HTTPError)(nil); errors.As(err, &httpErr) && httpErr.RetryAfter > 0 {
+		delay = max(delay, min(httpErr.RetryAfter, p.MaxDelay))
+	}
+
+	return delay, true
+}
+
+// isRetryable reports whether the request failed with 408, 429, 5xx or a connection reset
+func isRetryable(err error) bool {
+	if httpErr := (*HTTPError)(nil); errors.As(err, &httpErr) {
+		code := httpErr.StatusCode
+		return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// canFallback reports whether the turn can be retried with the next provider after err:
+// a retryable error, a timeout or a refused connection, unless ctx is done
+func canFallback(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var netErr net.Error
+	return isRetryable(err) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// parseRetryAfter parses the Retry-After header, either in seconds or as an HTTP date
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+
+	return 0, false
+}
+
+// sleep waits for d, returning early with the error of ctx if it is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
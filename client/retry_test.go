@@ -0,0 +1,126 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	tests := []struct {
+		name     string
+		attempt  int
+		err      error
+		retry    bool
+		min, max time.Duration
+	}{
+		{name: "429", attempt: 0, err: &HTTPError{StatusCode: 429}, retry: true, min: 500 * time.Millisecond, max: time.Second},
+		{name: "503 backoff", attempt: 2, err: &HTTPError{StatusCode: 503}, retry: true, min: 2 * time.Second, max: 4 * time.Second},
+		{name: "408", attempt: 0, err: &HTTPError{StatusCode: 408}, retry: true, min: 500 * time.Millisecond, max: time.Second},
+		{name: "connection reset", attempt: 0, err: fmt.Errorf("read: %w", syscall.ECONNRESET), retry: true,
+			min: 500 * time.Millisecond, max: time.Second},
+		{name: "retry after", attempt: 0, err: &HTTPError{StatusCode: 429, RetryAfter: 5 * time.Second}, retry: true,
+			min: 5 * time.Second, max: 5 * time.Second},
+		{name: "retry after capped", attempt: 0, err: &HTTPError{StatusCode: 429, RetryAfter: time.Minute}, retry: true,
+			min: 10 * time.Second, max: 10 * time.Second},
+		{name: "400", attempt: 0, err: &HTTPError{StatusCode: 400}},
+		{name: "exhausted", attempt: 3, err: &HTTPError{StatusCode: 500}},
+		{name: "other error", attempt: 0, err: errors.New("boom")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, retry := policy.delay(tt.attempt, tt.err)
+			if retry != tt.retry {
+				t.Fatalf("delay() retry = %v, want %v", retry, tt.retry)
+			}
+			if retry && (delay < tt.min || delay > tt.max) {
+				t.Errorf("delay() = %s, want between %s and %s", delay, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	if d, ok := parseRetryAfter("7", now); !ok || d != 7*time.Second {
+		t.Errorf("parseRetryAfter(7) = %s, %v, want 7s", d, ok)
+	}
+	if d, ok := parseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now); !ok || d != time.Minute {
+		t.Errorf("parseRetryAfter(date) = %s, %v, want 1m", d, ok)
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Error("parseRetryAfter(soon) should fail")
+	}
+}
+
+func TestOpenAIProvider_Retry(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"message":"rate limited"}}`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", openAIContentChunk("Hello!", "stop"))
+	}))
+	t.Cleanup(server.Close)
+
+	msg, err := newRetryTestProvider(server.URL).CallStreamableChatCompletions(
+		t.Context(), []*Message{NewMessageWithOption(RoleUser, "hi", nil)}, nil)
+	if err != nil {
+		t.Fatalf("CallStreamableChatCompletions() error = %v", err)
+	}
+	if msg.Content != "Hello!" || attempts.Load() != 2 {
+		t.Errorf("content = %q after %d attempts, want the answer of the retry", msg.Content, attempts.Load())
+	}
+}
+
+func TestOpenAIProvider_RetryExhausted(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, `{"error":{"message":"upstream is down"}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := newRetryTestProvider(server.URL).CallStreamableChatCompletions(
+		t.Context(), []*Message{NewMessageWithOption(RoleUser, "hi", nil)}, nil)
+
+	// NOTE: The error of the provider is surfaced to the user
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("CallStreamableChatCompletions() error = %v, want HTTPError 502", err)
+	}
+	if !strings.Contains(err.Error(), `"upstream is down"`) {
+		t.Errorf("error = %v, want the error JSON of the provider", err)
+	}
+	if attempts.Load() != 3 {
+		t.Errorf("attempts = %d, want 1 + 2 retries", attempts.Load())
+	}
+}
+
+func newRetryTestProvider(baseURL string) *OpenAIFormatProvider {
+	return NewOpenAIFormatProvider(&Config{
+		Provider:       ProviderOpenAI,
+		BaseURL:        baseURL,
+		CustomAPIPath:  DefaultCustomAPIPath,
+		Model:          "test-model",
+		Stream:         true,
+		MaxRetries:     2,
+		RetryBaseDelay: time.Millisecond,
+	}, &discardLogger{})
+}
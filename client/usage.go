@@ -0,0 +1,153 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// UsageDayLayout is the layout of UsageSummary.Day
+const UsageDayLayout = "2006-01-02"
+
+// Usage is the token usage of an assistant message, with the timings reported by Ollama
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`          // 包含推理 token
+	ReasoningTokens  int `json:"reasoning_tokens,omitempty"` // 推理 token
+
+	TotalDuration      time.Duration `json:"total_duration,omitempty"`       // Ollama: 总耗时
+	LoadDuration       time.Duration `json:"load_duration,omitempty"`        // Ollama: 加载模型耗时
+	PromptEvalDuration time.Duration `json:"prompt_eval_duration,omitempty"` // Ollama: 处理 prompt 耗时
+	EvalDuration       time.Duration `json:"eval_duration,omitempty"`        // Ollama: 生成回答耗时
+}
+
+// TotalTokens returns the number of prompt and completion tokens
+func (u *Usage) TotalTokens() int { return u.PromptTokens + u.CompletionTokens }
+
+// String returns the tokens of the usage, and the generation speed if timed by Ollama
+func (u *Usage) String() string {
+	str := fmt.Sprintf("%d prompt + %d completion tokens", u.PromptTokens, u.CompletionTokens)
+	if u.ReasoningTokens > 0 {
+		str += fmt.Sprintf(" (%d reasoning)", u.ReasoningTokens)
+	}
+	if u.TotalDuration > 0 {
+		str += fmt.Sprintf(", %s", u.TotalDuration.Round(time.Millisecond))
+	}
+	if u.EvalDuration > 0 {
+		str += fmt.Sprintf(", %.1f tokens/s", float64(u.CompletionTokens)/u.EvalDuration.Seconds())
+	}
+
+	return str
+}
+
+func (u *Usage) add(other *Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.ReasoningTokens += other.ReasoningTokens
+
+	u.TotalDuration += other.TotalDuration
+	u.LoadDuration += other.LoadDuration
+	u.PromptEvalDuration += other.PromptEvalDuration
+	u.EvalDuration += other.EvalDuration
+}
+
+// ModelPrice is the price of a model in USD per million tokens, configured in the prices of client.yaml
+type ModelPrice struct {
+	Model  string  `mapstructure:"model"`  // 与回答中记录的 model 完全一致
+	Input  float64 `mapstructure:"input"`  // 每百万 prompt token 的价格
+	Output float64 `mapstructure:"output"` // 每百万 completion token 的价格，包含推理 token
+}
+
+// Cost returns the cost of the usage in USD
+func (p *ModelPrice) Cost(usage *Usage) float64 {
+	return (float64(usage.PromptTokens)*p.Input + float64(usage.CompletionTokens)*p.Output) / 1e6
+}
+
+// priceOf returns the price of the model, nil if not configured
+func priceOf(prices []*ModelPrice, model string) *ModelPrice {
+	for _, price := range prices {
+		if price != nil && price.Model == model {
+			return price
+		}
+	}
+
+	return nil
+}
+
+// UsageSummary is the usage summed over the assistant messages
+type UsageSummary struct {
+	Usage
+
+	Day      string  // 按天统计时的日期 (UsageDayLayout，本地时间)，总计时为空
+	Messages int     // 带 usage 的回答数
+	Cost     float64 // USD
+	Unpriced int     // 没有配置价格的回答数
+}
+
+// String returns the answers, tokens and cost of the summary
+func (s *UsageSummary) String() string {
+	str := fmt.Sprintf("%d answers, %d prompt + %d completion tokens", s.Messages, s.PromptTokens, s.CompletionTokens)
+	if s.ReasoningTokens > 0 {
+		str += fmt.Sprintf(" (%d reasoning)", s.ReasoningTokens)
+	}
+	str += fmt.Sprintf(", $%.4f", s.Cost)
+	if s.Unpriced > 0 {
+		str += fmt.Sprintf(" (%d answers without price)", s.Unpriced)
+	}
+
+	return str
+}
+
+func (s *UsageSummary) add(msg *Message, prices []*ModelPrice) {
+	s.Messages++
+	s.Usage.add(msg.Usage)
+
+	if price := priceOf(prices, msg.Model); price != nil {
+		s.Cost += price.Cost(msg.Usage)
+	} else {
+		s.Unpriced++
+	}
+}
+
+// SummarizeUsage sums the usage of the assistant messages of the chats, in total and per day.
+// The days are in ascending order, the cost is computed with the prices.
+func SummarizeUsage(chats []*Chat, prices []*ModelPrice) (*UsageSummary, []*UsageSummary) {
+	total := &UsageSummary{}
+	byDay := make(map[string]*UsageSummary)
+
+	for _, chat := range chats {
+		for _, msg := range chat.Messages {
+			if msg.Role != RoleAssistant || msg.Usage == nil {
+				continue
+			}
+
+			day := messageDay(chat, msg)
+			if byDay[day] == nil {
+				byDay[day] = &UsageSummary{Day: day}
+			}
+
+			total.add(msg, prices)
+			byDay[day].add(msg, prices)
+		}
+	}
+
+	days := make([]*UsageSummary, 0, len(byDay))
+	for _, summary := range byDay {
+		days = append(days, summary)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Day < days[j].Day })
+
+	return total, days
+}
+
+// messageDay returns the local day of the message, the day of the chat if the message has no timestamp
+func messageDay(chat *Chat, msg *Message) string {
+	switch {
+	case msg.Timestamp != nil && !msg.Timestamp.IsZero():
+		return msg.Timestamp.Local().Format(UsageDayLayout)
+	case msg.UnixTimestamp > 0:
+		return time.UnixMilli(msg.UnixTimestamp).Local().Format(UsageDayLayout)
+	default:
+		return chat.CreateTime.Local().Format(UsageDayLayout)
+	}
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizeUsage(t *testing.T) {
+	day1 := time.Date(2026, 10, 14, 12, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	answer := func(model string, at time.Time, prompt, completion int) *Message {
+		msg := NewMessage(RoleAssistant, "answer", at, at.UnixMilli())
+		msg.Model = model
+		msg.Usage = &Usage{PromptTokens: prompt, CompletionTokens: completion}
+		return msg
+	}
+
+	chats := []*Chat{
+		{ID: "a", Messages: []*Message{
+			NewMessage(RoleUser, "question", day1, day1.UnixMilli()),
+			answer("priced", day1, 1000, 500),
+			answer("free", day2, 200, 100),
+		}},
+		{ID: "b", CreateTime: day2, Messages: []*Message{
+			{Role: RoleAssistant, Model: "priced", Usage: &Usage{PromptTokens: 1000, CompletionTokens: 500}},
+		}},
+	}
+	prices := []*ModelPrice{{Model: "priced", Input: 1, Output: 2}}
+
+	total, daily := SummarizeUsage(chats, prices)
+	if total.Messages != 3 || total.PromptTokens != 2200 || total.CompletionTokens != 1100 || total.Unpriced != 1 {
+		t.Errorf("total = %+v, want 3 answers, 2200 + 1100 tokens and 1 without price", total)
+	}
+	if want := 2 * (1000*1.0 + 500*2.0) / 1e6; total.Cost != want {
+		t.Errorf("total cost = %f, want %f", total.Cost, want)
+	}
+
+	// NOTE: The message without timestamp is counted on the day of its chat
+	if len(daily) != 2 || daily[0].Day != day1.Format(UsageDayLayout) || daily[1].Day != day2.Format(UsageDayLayout) {
+		t.Fatalf("daily = %+v, want the two days in ascending order", daily)
+	}
+	if daily[0].Messages != 1 || daily[1].Messages != 2 || daily[1].PromptTokens != 1200 {
+		t.Errorf("daily = %+v, %+v, want 1 answer then 2 answers", daily[0], daily[1])
+	}
+}
+
+func TestUsage_String(t *testing.T) {
+	usage := &Usage{
+		PromptTokens:     10,
+		CompletionTokens: 40,
+		ReasoningTokens:  5,
+		TotalDuration:    2 * time.Second,
+		EvalDuration:     time.Second,
+	}
+	if got, want := usage.String(), "10 prompt + 40 completion tokens (5 reasoning), 2s, 40.0 tokens/s"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
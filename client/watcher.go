@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kydenul/log"
+)
+
+// DefaultReloadDelay is the time to wait for the writes of a file to settle before reloading it
+const DefaultReloadDelay = 200 * time.Millisecond
+
+// FileWatcher calls the handler of a file after it is written or replaced.
+// The directories of the files are watched, so that the files replaced by editors on save are still watched.
+type FileWatcher struct {
+	log.Logger
+
+	watcher *fsnotify.Watcher
+	delay   time.Duration
+
+	mu       sync.Mutex
+	handlers map[string]func()      // 文件绝对路径 => 文件变更后的回调
+	timers   map[string]*time.Timer // 合并短时间内的多次写入
+}
+
+// NewFileWatcher returns a file watcher calling the handlers delay after the last change of a file
+func NewFileWatcher(delay time.Duration, logger log.Logger) (*FileWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	return &FileWatcher{
+		Logger: logger,
+
+		watcher: watcher,
+		delay:   delay,
+
+		handlers: make(map[string]func()),
+		timers:   make(map[string]*time.Timer),
+	}, nil
+}
+
+// Add watches the file, handler is called after the file is changed
+func (w *FileWatcher) Add(path string, handler func()) error {
+	path, err := ExpandUser(path)
+	if err != nil {
+		return fmt.Errorf("failed to expand user: %w", err)
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return fmt.Errorf("failed to get absolute path of %s: %w", path, err)
+	}
+
+	if err := w.watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", path, err)
+	}
+
+	w.mu.Lock()
+	w.handlers[path] = handler
+	w.mu.Unlock()
+
+	w.Infof("Watching %s", path)
+
+	return nil
+}
+
+// Run dispatches the changes of the files until ctx is done, then closes the watcher
+func (w *FileWatcher) Run(ctx context.Context) {
+	defer w.close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
+				w.schedule(filepath.Clean(event.Name))
+			}
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.Errorf("File watcher error: %v", err)
+		}
+	}
+}
+
+// schedule calls the handler of the file after delay, postponed by the following changes
+func (w *FileWatcher) schedule(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	handler, ok := w.handlers[path]
+	if !ok {
+		return
+	}
+
+	if timer, ok := w.timers[path]; ok {
+		timer.Stop()
+	}
+	w.timers[path] = time.AfterFunc(w.delay, func() {
+		w.Infof("%s changed, reloading", path)
+		handler()
+	})
+}
+
+func (w *FileWatcher) close() {
+	w.mu.Lock()
+	for _, timer := range w.timers {
+		timer.Stop()
+	}
+	w.mu.Unlock()
+
+	if err := w.watcher.Close(); err != nil {
+		w.Errorf("Failed to close file watcher: %v", err)
+	}
+}
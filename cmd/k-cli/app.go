@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/kydenul/log"
+
+	"github.com/kydenul/K-CLI/client"
+)
+
+const (
+	DefaultConfigDir = "~/.config/k-cli"
+
+	ClientFileName  = "client.yaml"
+	ChatsFileName   = "chats.jsonl"
+	ChatsDBFileName = "chats.db"
+	MCPSvrFileName  = "mcp_servers.jsonl"
+	PromptFileName  = "prompts.jsonl"
+	HistoryFileName = "history"
+
+	DefaultChatRepoWorkerCount = 4
+)
+
+// App bundles the logger, config and repositories shared by every k-cli command
+type App struct {
+	Logger *log.Log
+	Config *client.Config
+
+	ChatRepo   client.ChatRepo
+	MCPRepo    *client.MCPSvrConfigFileRepo
+	PromptRepo *client.PromptFileRepo
+
+	configDir string
+}
+
+// NewApp loads client.yaml, chats.jsonl, mcp_servers.jsonl and prompts.jsonl from configDir,
+// applying the profile of client.yaml, default_profile if profile is empty
+func NewApp(configDir, profile string) (*App, error) {
+	dir, err := client.ExpandUser(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand config dir %s: %w", configDir, err)
+	}
+
+	clientPath := filepath.Join(dir, ClientFileName)
+	if err := client.EnsureFileExistsSync(clientPath); err != nil {
+		return nil, fmt.Errorf("failed to ensure %s exists: %w", clientPath, err)
+	}
+
+	// NOTE: Initialize Logger
+	opt, err := log.LoadFromFile(clientPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load log config from %s: %w", clientPath, err)
+	}
+	// NOTE: stdout is reserved for the output of the commands, e.g. ask --json, the logs only go to the log files
+	logger := log.NewLog(opt.WithConsoleOutput(false))
+
+	// NOTE: Initialize Config
+	config, err := client.NewConfigFromFile(clientPath, logger)
+	if err != nil {
+		return nil, err
+	}
+	if config, err = config.WithProfile(profile); err != nil {
+		return nil, err
+	}
+
+	// NOTE: Initialize Chat Repository
+	chatRepo, err := newChatRepo(dir, config.StorageType, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize chat repository: %w", err)
+	}
+
+	// NOTE: Initialize MCP Server Config Repository
+	mcpRepo, err := client.NewMCPSvrConfigFileRepo(filepath.Join(dir, MCPSvrFileName), logger)
+	if err != nil {
+		_ = chatRepo.Close()
+		return nil, fmt.Errorf("failed to initialize mcp server repository: %w", err)
+	}
+
+	// NOTE: Initialize Prompt Repository
+	promptRepo, err := client.NewPromptFileRepo(filepath.Join(dir, PromptFileName), logger)
+	if err != nil {
+		_ = chatRepo.Close()
+		return nil, fmt.Errorf("failed to initialize prompt repository: %w", err)
+	}
+
+	logger.Infof("k-cli %s (%s) initialized with config dir %s", Version, BuildTime, dir)
+
+	return &App{
+		Logger: logger,
+		Config: config,
+
+		ChatRepo:   chatRepo,
+		MCPRepo:    mcpRepo,
+		PromptRepo: promptRepo,
+
+		configDir: dir,
+	}, nil
+}
+
+// newChatRepo creates the chat repository of storage_type, chats.db imports chats.jsonl the first time
+func newChatRepo(dir, storageType string, logger log.Logger) (client.ChatRepo, error) {
+	if storageType != client.StorageTypeSQLite {
+		return client.NewChatFileRepository(filepath.Join(dir, ChatsFileName), DefaultChatRepoWorkerCount, logger)
+	}
+
+	repo, err := client.NewChatSQLiteRepository(filepath.Join(dir, ChatsDBFileName), logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := repo.MigrateFromJSONL(context.Background(), filepath.Join(dir, ChatsFileName)); err != nil {
+		_ = repo.Close()
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// NewManager creates a client.Manager, continuing the chat with chatID if it is not empty
+func (app *App) NewManager(chatID string) (*client.Manager, error) {
+	var id *string
+	if chatID != "" {
+		// NOTE: Check the chat before connecting the MCP servers, the first turn would fail anyway
+		chat, err := app.ChatRepo.Chat(context.Background(), chatID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load chat %s: %w", chatID, err)
+		}
+		if chat == nil {
+			return nil, fmt.Errorf("chat %s not found, run 'k-cli chats list' to see the chats", chatID)
+		}
+
+		id = &chatID
+	}
+
+	return client.NewManager(app.Logger, app.ChatRepo, app.MCPRepo, app.PromptRepo, id, app.Config)
+}
+
+// HistoryPath returns the path of the REPL history file
+func (app *App) HistoryPath() string {
+	return filepath.Join(app.configDir, HistoryFileName)
+}
+
+// Close releases the repositories and flushes the logger
+func (app *App) Close() {
+	if err := app.ChatRepo.Close(); err != nil {
+		app.Logger.Errorf("failed to close chat repository: %v", err)
+	}
+
+	app.Logger.Sync()
+}
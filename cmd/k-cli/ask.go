@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+)
+
+// StdinArg is the argument of the ask subcommand appending stdin to the question
+const StdinArg = "-"
+
+// askOptions holds the flags of the ask subcommand
+type askOptions struct {
+	chatID     string
+	promptName string
+	json       bool
+	timeout    time.Duration
+
+	hideReasoning bool
+}
+
+// askResult is the output of the ask subcommand in JSON mode
+type askResult struct {
+	ChatID   string `json:"chat_id"`
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Content  string `json:"content"`
+
+	Reasoning string `json:"reasoning,omitempty"`
+}
+
+// NewAskCmd returns the ask subcommand, which answers one question non-interactively
+func NewAskCmd(root *rootOptions) *cobra.Command {
+	opts := &askOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "ask [question] [-]",
+		Short: "Ask a single question and print the answer",
+		Long: "Ask a single question and print only the final answer to stdout.\n\n" +
+			"The question is read from stdin if no question is given, and stdin is appended\n" +
+			"to the question if an argument is \"-\", e.g.\n" +
+			"  git diff | k-cli ask \"review this\" -",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			input, err := readAskInput(args, cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+
+			app, err := NewApp(root.configDir, root.profile)
+			if err != nil {
+				return err
+			}
+			defer app.Close()
+
+			// NOTE: Ctrl-C or the timeout aborts the request and the tool call in flight
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			if opts.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+				defer cancel()
+			}
+
+			opts.hideReasoning = root.hideReasoning
+			return runAsk(ctx, app, opts, input, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.chatID, "chat", "c", "", "continue an existing chat by ID")
+	cmd.Flags().StringVarP(&opts.promptName, "prompt", "p", "", "name of the prompt appended to the system prompt")
+	cmd.Flags().BoolVar(&opts.json, "json", false, "print the answer as JSON")
+	cmd.Flags().DurationVarP(&opts.timeout, "timeout", "t", 0, "abort if no answer within the duration, e.g. 2m")
+
+	return cmd
+}
+
+func runAsk(ctx context.Context, app *App, opts *askOptions, input string, out io.Writer) error {
+	mgr, err := app.NewManager(opts.chatID)
+	if err != nil {
+		return err
+	}
+	defer mgr.MCPMgr.ClossAllSession()
+
+	if opts.promptName != "" {
+		if err := mgr.SetPrompt(opts.promptName); err != nil {
+			return err
+		}
+	}
+
+	msg, err := mgr.HandleUserTextInput(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	if !opts.json {
+		_, err := fmt.Fprintln(out, cast.ToString(msg.Content))
+		return err
+	}
+
+	data, err := sonic.MarshalIndent(&askResult{
+		ChatID:   mgr.ChatID(),
+		Provider: msg.Provider,
+		Model:    msg.Model,
+		Content:  cast.ToString(msg.Content),
+
+		Reasoning: lo.Ternary(opts.hideReasoning, "", msg.Reasoning()),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal answer: %w", err)
+	}
+
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// readAskInput joins the question from args with the content of stdin. Stdin is only read if an argument is
+// StdinArg, or if no question is given and stdin is not a terminal, so that the open stdin of cron, CI
+// or `ssh -T` never blocks a question given as an argument.
+func readAskInput(args []string, stdin io.Reader) (string, error) {
+	readStdin := len(args) == 0 && isPiped(stdin)
+	words := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == StdinArg {
+			readStdin = true
+			continue
+		}
+		words = append(words, arg)
+	}
+
+	parts := make([]string, 0, 2)
+	if question := strings.TrimSpace(strings.Join(words, " ")); question != "" {
+		parts = append(parts, question)
+	}
+
+	if readStdin {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read stdin: %w", err)
+		}
+
+		if piped := strings.TrimSpace(string(data)); piped != "" {
+			parts = append(parts, piped)
+		}
+	}
+
+	if len(parts) == 0 {
+		return "", errors.New("no question given, pass it as an argument or through stdin")
+	}
+
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// isPiped reports whether r is not an interactive terminal
+func isPiped(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return true
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice == 0
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/sonic"
+)
+
+// newTestConfigDir writes a client.yaml pointing the OpenAI provider at baseURL,
+// the extra lines are appended to the K-CLI settings
+func newTestConfigDir(t *testing.T, baseURL string, extra ...string) string {
+	dir := t.TempDir()
+
+	yaml := fmt.Sprintf(`directory: %q
+level: "error"
+console_output: false
+
+K-CLI:
+  provider: "OpenAI"
+  model: "test-model"
+  base_url: %q
+  custom_api_path: "/v1/chat/completions"
+  stream: true
+  max_turns: 3
+  max_retries: 0
+`, filepath.Join(dir, "logs"), baseURL)
+	for _, line := range extra {
+		yaml += "  " + line + "\n"
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ClientFileName), []byte(yaml), 0o600); err != nil {
+		t.Fatalf("failed to write client.yaml: %v", err)
+	}
+
+	return dir
+}
+
+// newOpenAIStub returns a server streaming the given chunks as OpenAI SSE events
+func newOpenAIStub(t *testing.T, status int, chunks ...string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(status)
+
+		for idx, chunk := range chunks {
+			finish := ""
+			if idx == len(chunks)-1 {
+				finish = "stop"
+			}
+
+			fmt.Fprintf(w,
+				"data: {\"id\":\"resp-1\",\"model\":\"test-model\",\"choices\":[{\"index\":0,"+
+					"\"delta\":{\"content\":%q},\"finish_reason\":%q}]}\n\n", chunk, finish)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestReadAskInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "question only",
+			args:     []string{"what", "is", "MCP?"},
+			expected: "what is MCP?",
+		},
+		{
+			name:     "question and stdin",
+			args:     []string{"review this", "-"},
+			stdin:    "diff --git a/main.go b/main.go\n",
+			expected: "review this\n\ndiff --git a/main.go b/main.go",
+		},
+		{
+			name:     "stdin ignored with a question",
+			args:     []string{"review this"},
+			stdin:    "left open by cron",
+			expected: "review this",
+		},
+		{
+			name:     "stdin only",
+			stdin:    "hello",
+			expected: "hello",
+		},
+		{
+			name:    "nothing",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAskInput(tt.args, strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readAskInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("readAskInput() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAskCmd(t *testing.T) {
+	server := newOpenAIStub(t, http.StatusOK, "Hello", ", world")
+	dir := newTestConfigDir(t, server.URL)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "plain output",
+			args:     []string{"ask", "--config-dir", dir, "hi"},
+			expected: "Hello, world\n",
+		},
+		{
+			name:     "stdin argument",
+			args:     []string{"ask", "--config-dir", dir, "hi", "-"},
+			expected: "Hello, world\n",
+		},
+		{
+			name:     "json output",
+			args:     []string{"ask", "--config-dir", dir, "--json", "hi"},
+			expected: `"content": "Hello, world"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+
+			cmd := NewRootCmd()
+			cmd.SetArgs(tt.args)
+			cmd.SetIn(strings.NewReader(""))
+			cmd.SetOut(out)
+
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if !strings.Contains(out.String(), tt.expected) {
+				t.Errorf("Execute() output = %q, want %q", out.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestAskCmd_JSONStdout(t *testing.T) {
+	server := newOpenAIStub(t, http.StatusOK, "Hello")
+	dir := newTestConfigDir(t, server.URL)
+
+	// NOTE: The logs must not reach stdout even if client.yaml enables the console output
+	clientPath := filepath.Join(dir, ClientFileName)
+	data, err := os.ReadFile(clientPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yaml := strings.NewReplacer(`level: "error"`, `level: "debug"`, "console_output: false", "console_output: true").
+		Replace(string(data))
+	if err := os.WriteFile(clientPath, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+
+	saved := os.Stdout
+	os.Stdout = stdout
+	defer func() { os.Stdout = saved }()
+
+	cmd := NewRootCmd()
+	cmd.SetArgs([]string{"ask", "--config-dir", dir, "--json", "hi"})
+	cmd.SetIn(strings.NewReader(""))
+	err = cmd.Execute()
+	os.Stdout = saved
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	output, _ := os.ReadFile(stdout.Name())
+	result := &askResult{}
+	if err := sonic.Unmarshal(output, result); err != nil || result.Content != "Hello" {
+		t.Errorf("stdout = %q is not the JSON answer: %v", output, err)
+	}
+}
+
+func TestAskCmd_Profile(t *testing.T) {
+	failing := newOpenAIStub(t, http.StatusTooManyRequests)
+	server := newOpenAIStub(t, http.StatusOK, "Hello from the profile")
+	dir := newTestConfigDir(t, failing.URL,
+		"profiles:",
+		"  local:",
+		"    base_url: "+server.URL,
+		"    model: profile-model",
+	)
+
+	out := &bytes.Buffer{}
+	cmd := NewRootCmd()
+	cmd.SetArgs([]string{"--config-dir", dir, "--profile", "local", "ask", "hi"})
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetOut(out)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if out.String() != "Hello from the profile\n" {
+		t.Errorf("Execute() output = %q, want the answer of the profile", out.String())
+	}
+
+	cmd = NewRootCmd()
+	cmd.SetArgs([]string{"--config-dir", dir, "--profile", "remote", "ask", "hi"})
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "unknown profile 'remote'") {
+		t.Errorf("Execute() error = %v, want the unknown profile", err)
+	}
+}
+
+func TestAskCmd_ProviderFailure(t *testing.T) {
+	server := newOpenAIStub(t, http.StatusTooManyRequests)
+	dir := newTestConfigDir(t, server.URL)
+
+	out := &bytes.Buffer{}
+
+	cmd := NewRootCmd()
+	cmd.SetArgs([]string{"ask", "--config-dir", dir, "hi"})
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetOut(out)
+	cmd.SetErr(&bytes.Buffer{})
+
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("Execute() should fail when the provider fails")
+	}
+	if out.Len() != 0 {
+		t.Errorf("Execute() should not print to stdout on failure, got %q", out.String())
+	}
+}
+
+func TestChatFlag_UnknownChat(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+	dir := newTestConfigDir(t, server.URL)
+
+	for _, args := range [][]string{
+		{"--config-dir", dir, "--chat", "missing"},
+		{"ask", "--config-dir", dir, "--chat", "missing", "hi"},
+	} {
+		out, err := executeRootCmd(t, args...)
+		if err == nil || !strings.Contains(err.Error(), "chat missing not found") {
+			t.Errorf("Execute(%v) error = %v, want chat missing not found", args, err)
+		}
+		if out != "" {
+			t.Errorf("Execute(%v) output = %q, want nothing", args, out)
+		}
+	}
+
+	if requests != 0 {
+		t.Errorf("provider got %d requests, want none", requests)
+	}
+}
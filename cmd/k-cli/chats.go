@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+
+	"github.com/kydenul/K-CLI/client"
+)
+
+const listTimeLayout = "2006-01-02 15:04"
+
+// NewChatsCmd returns the chats subcommand family, which manages the chat history
+func NewChatsCmd(root *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "chats",
+		Short: "Manage the chat history",
+	}
+
+	cmd.AddCommand(
+		newChatsListCmd(root),
+		newChatsShowCmd(root),
+		newChatsRmCmd(root),
+		newChatsExportCmd(root),
+		newChatsImportCmd(root),
+	)
+
+	return cmd
+}
+
+// withChatSvr runs fn with a chat service backed by the chat repository of the app
+func withChatSvr(root *rootOptions, fn func(app *App, svr *client.ChatSvr) error) error {
+	app, err := NewApp(root.configDir, root.profile)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	return fn(app, client.NewChatSvr(app.ChatRepo, app.Logger))
+}
+
+func newChatsListCmd(root *rootOptions) *cobra.Command {
+	var (
+		keyword, model, provider string
+		limit                    int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List chats, most recent first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if limit <= 0 {
+				return errors.New("--limit must be greater than 0")
+			}
+
+			return withChatSvr(root, func(_ *App, svr *client.ChatSvr) error {
+				chats, err := svr.ListChats(cmd.Context(),
+					optionalFlag(cmd, "keyword", keyword),
+					optionalFlag(cmd, "model", model),
+					optionalFlag(cmd, "provider", provider),
+					limit)
+				if err != nil {
+					return fmt.Errorf("failed to list chats: %w", err)
+				}
+
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "ID\tUPDATED\tMESSAGES\tTITLE")
+				for _, chat := range chats {
+					fmt.Fprintf(w, "%s\t%s\t%d\t%s\n",
+						chat.ID, chat.UpdateTime.Format(listTimeLayout), len(chat.Messages), chat.Title())
+				}
+
+				return w.Flush()
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyword, "keyword", "k", "", "only list chats containing the keyword")
+	cmd.Flags().StringVarP(&model, "model", "m", "", "only list chats answered by the model")
+	cmd.Flags().StringVarP(&provider, "provider", "p", "", "only list chats answered by the provider")
+	cmd.Flags().IntVarP(&limit, "limit", "n", client.DefaultListChatsLimit, "maximum number of chats to list")
+
+	return cmd
+}
+
+func newChatsShowCmd(root *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <chat-id>",
+		Short: "Show the messages of a chat",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withChatSvr(root, func(app *App, svr *client.ChatSvr) error {
+				chat, err := svr.Chat(cmd.Context(), args[0])
+				if err != nil {
+					return err
+				}
+				if chat == nil {
+					return fmt.Errorf("chat %s not found", args[0])
+				}
+
+				out := cmd.OutOrStdout()
+				fmt.Fprintf(out, "Chat %s, created %s, updated %s\n",
+					chat.ID, chat.CreateTime.Format(listTimeLayout), chat.UpdateTime.Format(listTimeLayout))
+
+				for _, msg := range chat.Messages {
+					fmt.Fprintf(out, "\n[%s]", msg.Role)
+					if msg.Model != "" {
+						fmt.Fprintf(out, " %s/%s", msg.Provider, msg.Model)
+					}
+					if msg.Tool != "" {
+						fmt.Fprintf(out, " tool=%s/%s", msg.Server, msg.Tool)
+					}
+					if msg.Usage != nil {
+						fmt.Fprintf(out, " (%s)", msg.Usage)
+					}
+					if reasoning := strings.TrimSpace(msg.Reasoning()); reasoning != "" && !root.hideReasoning {
+						fmt.Fprintf(out, "\n💭 %s\n", strings.ReplaceAll(reasoning, "\n", "\n   "))
+					}
+					fmt.Fprintf(out, "\n%s\n", strings.TrimSpace(cast.ToString(msg.Content)))
+				}
+
+				total, daily := client.SummarizeUsage([]*client.Chat{chat}, app.Config.Prices)
+				if total.Messages > 0 {
+					fmt.Fprintf(out, "\nUsage: %s\n", total)
+					for _, day := range daily {
+						fmt.Fprintf(out, "  %s  %s\n", day.Day, day)
+					}
+				}
+
+				return nil
+			})
+		},
+	}
+}
+
+func newChatsRmCmd(root *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:     "rm <chat-id>...",
+		Aliases: []string{"delete"},
+		Short:   "Delete chats",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withChatSvr(root, func(_ *App, svr *client.ChatSvr) error {
+				for _, chatID := range args {
+					deleted, err := svr.DeleteChat(cmd.Context(), chatID)
+					if err != nil {
+						return fmt.Errorf("failed to delete chat %s: %w", chatID, err)
+					}
+					if !deleted {
+						return fmt.Errorf("chat %s not found", chatID)
+					}
+
+					fmt.Fprintf(cmd.OutOrStdout(), "Deleted chat %s\n", chatID)
+				}
+
+				return nil
+			})
+		},
+	}
+}
+
+func newChatsExportCmd(root *rootOptions) *cobra.Command {
+	var format, output string
+
+	cmd := &cobra.Command{
+		Use:   "export <chat-id>",
+		Short: "Export a chat as Markdown, JSON or HTML",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withChatSvr(root, func(_ *App, svr *client.ChatSvr) error {
+				content, err := svr.ExportChat(cmd.Context(), args[0], format)
+				if err != nil {
+					return err
+				}
+
+				if output == "" {
+					_, err := fmt.Fprintln(cmd.OutOrStdout(), content)
+					return err
+				}
+
+				if err := os.WriteFile(output, []byte(content+"\n"), 0o600); err != nil {
+					return fmt.Errorf("failed to write %s: %w", output, err)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "Exported chat %s to %s\n", args[0], output)
+
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", client.ExportFormatMarkdown,
+		"export format, one of "+strings.Join(client.ExportFormats, "|"))
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write to the file instead of stdout")
+
+	return cmd
+}
+
+func newChatsImportCmd(root *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import chats from a JSON export or a chats.jsonl file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chats, err := readChats(args[0])
+			if err != nil {
+				return err
+			}
+
+			return withChatSvr(root, func(_ *App, svr *client.ChatSvr) error {
+				for _, chat := range chats {
+					imported, err := svr.ImportChat(cmd.Context(), chat)
+					if err != nil {
+						return fmt.Errorf("failed to import chat %s: %w", chat.ID, err)
+					}
+
+					fmt.Fprintf(cmd.OutOrStdout(), "Imported chat %s\n", imported.ID)
+				}
+
+				return nil
+			})
+		},
+	}
+}
+
+// readChats reads chats from a file containing either one JSON document or one chat per line
+func readChats(file string) ([]*client.Chat, error) {
+	data, err := os.ReadFile(file) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	// NOTE: A single (possibly indented) JSON document, e.g. from `chats export --format json`
+	chat := &client.Chat{}
+	if err := sonic.Unmarshal(data, chat); err == nil {
+		return []*client.Chat{chat}, nil
+	}
+
+	// NOTE: JSONL, e.g. another chats.jsonl
+	chats := make([]*client.Chat, 0, 16)
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), len(data)+1)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		chat := &client.Chat{}
+		if err := sonic.UnmarshalString(line, chat); err != nil {
+			return nil, fmt.Errorf("failed to parse %s line %d: %w", file, lineNum, err)
+		}
+		chats = append(chats, chat)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	return chats, nil
+}
+
+// optionalFlag returns a pointer to value if the flag was set, otherwise nil
+func optionalFlag(cmd *cobra.Command, name, value string) *string {
+	if !cmd.Flags().Changed(name) {
+		return nil
+	}
+
+	return &value
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadChats(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name: "json export",
+			content: `{
+  "id": "abc123",
+  "Messages": [{"role": "user", "content": "hi"}]
+}`,
+			expected: []string{"abc123"},
+		},
+		{
+			name: "jsonl",
+			content: `{"id":"abc123","Messages":[{"role":"user","content":"hi"}]}
+
+{"id":"def456","Messages":[{"role":"user","content":"hello"}]}
+`,
+			expected: []string{"abc123", "def456"},
+		},
+		{
+			name:    "invalid line",
+			content: "{\"id\":\"abc123\"}\nnot json\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "chats.jsonl")
+			if err := os.WriteFile(file, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+
+			chats, err := readChats(file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readChats() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(chats) != len(tt.expected) {
+				t.Fatalf("readChats() got %d chats, want %d", len(chats), len(tt.expected))
+			}
+			for idx, chat := range chats {
+				if chat.ID != tt.expected[idx] {
+					t.Errorf("readChats()[%d].ID = %s, want %s", idx, chat.ID, tt.expected[idx])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"os"
+)
+
+// Injected at build time via -ldflags, see Makefile
+var (
+	Version   = "dev"
+	BuildTime = "unknown"
+)
+
+func main() {
+	if err := NewRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
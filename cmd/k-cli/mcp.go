@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kydenul/K-CLI/client"
+)
+
+// DefaultMCPTestTimeout is the default timeout of `mcp test`
+const DefaultMCPTestTimeout = 30 * time.Second
+
+// NewMCPCmd returns the mcp subcommand family, which manages the MCP server registry
+func NewMCPCmd(root *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mcp",
+		Short: "Manage the MCP servers",
+	}
+
+	cmd.AddCommand(
+		newMCPListCmd(root),
+		newMCPAddCmd(root),
+		newMCPToggleCmd(root, "enable", true),
+		newMCPToggleCmd(root, "disable", false),
+		newMCPRmCmd(root),
+		newMCPTestCmd(root),
+	)
+
+	return cmd
+}
+
+// withMCPConfigSvr runs fn with an MCP config service backed by the MCP server repository of the app
+func withMCPConfigSvr(root *rootOptions, fn func(app *App, svr *client.MCPConfigSvr) error) error {
+	app, err := NewApp(root.configDir, root.profile)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	return fn(app, client.NewMCPSvr(app.MCPRepo, app.Logger))
+}
+
+func newMCPListCmd(root *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the configured MCP servers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withMCPConfigSvr(root, func(_ *App, svr *client.MCPConfigSvr) error {
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "NAME\tTYPE\tACTIVE\tTARGET\tDESCRIPTION")
+				for _, item := range svr.AllMCPServerConfig() {
+					fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n",
+						item.Name, item.Type, item.IsActive, mcpTarget(item), item.Description)
+				}
+
+				return w.Flush()
+			})
+		},
+	}
+}
+
+func newMCPAddCmd(root *rootOptions) *cobra.Command {
+	var (
+		description string
+		disabled    bool
+		sse         bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "Add an MCP server",
+	}
+	cmd.PersistentFlags().StringVarP(&description, "description", "d", "", "description of the server")
+	cmd.PersistentFlags().BoolVar(&disabled, "disabled", false, "add the server without activating it")
+
+	stdio := &cobra.Command{
+		Use:   "stdio <name> -- <command> [args...]",
+		Short: "Add an MCP server started as a subprocess and spoken to over stdio",
+		Example: "  k-cli mcp add stdio fetch -- uvx mcp-server-fetch\n" +
+			"  k-cli mcp add stdio fs -- npx -y @modelcontextprotocol/server-filesystem ~/notes",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cmd.ArgsLenAtDash() != 1 || len(args) < 2 {
+				return errors.New("expected <name> -- <command> [args...]")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return addMCPServer(root, cmd.OutOrStdout(), &client.MCPSvrItem{
+				Name:        args[0],
+				Type:        client.ServerTypeStdio,
+				IsActive:    !disabled,
+				Description: description,
+				Command:     args[1],
+				Args:        args[2:],
+			})
+		},
+	}
+
+	httpCmd := &cobra.Command{
+		Use:   "http <name> <url>",
+		Short: "Add a remote MCP server spoken to over Streamable HTTP or SSE",
+		Example: "  k-cli mcp add http search https://example.com/mcp\n" +
+			"  k-cli mcp add http legacy http://localhost:8080/sse --sse",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if u, err := url.Parse(args[1]); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("invalid url '%s', expected an http or https URL", args[1])
+			}
+
+			typ := client.ServerTypeStreamableHTTP
+			if sse {
+				typ = client.ServerTypeSSE
+			}
+
+			return addMCPServer(root, cmd.OutOrStdout(), &client.MCPSvrItem{
+				Name:        args[0],
+				Type:        typ,
+				IsActive:    !disabled,
+				Description: description,
+				BaseURL:     args[1],
+			})
+		},
+	}
+	httpCmd.Flags().BoolVar(&sse, "sse", false, "use the legacy SSE transport instead of Streamable HTTP")
+
+	cmd.AddCommand(stdio, httpCmd)
+
+	return cmd
+}
+
+// addMCPServer adds the server to the registry, failing if a server with the same name exists
+func addMCPServer(root *rootOptions, out io.Writer, item *client.MCPSvrItem) error {
+	return withMCPConfigSvr(root, func(_ *App, svr *client.MCPConfigSvr) error {
+		if svr.MCPServerConfigByName(item.Name) != nil {
+			return fmt.Errorf("MCP server %s already exists", item.Name)
+		}
+
+		if err := svr.CreateMCPServerConfig(
+			item.Name,
+			item.Type,
+			item.IsActive,
+
+			&item.Description,
+			&item.BaseURL,
+			&item.Command,
+			item.Args,
+		); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Added MCP server %s (%s)\n", item.Name, mcpTarget(item))
+
+		return nil
+	})
+}
+
+func newMCPToggleCmd(root *rootOptions, use string, active bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " <name>...",
+		Short: strings.ToUpper(use[:1]) + use[1:] + " MCP servers",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMCPConfigSvr(root, func(_ *App, svr *client.MCPConfigSvr) error {
+				for _, name := range args {
+					item := svr.MCPServerConfigByName(name)
+					if item == nil {
+						return fmt.Errorf("MCP server %s not found", name)
+					}
+
+					item.IsActive = active
+					if err := svr.UpdateMCPServerConfigByName(item); err != nil {
+						return fmt.Errorf("failed to %s MCP server %s: %w", use, name, err)
+					}
+
+					fmt.Fprintf(cmd.OutOrStdout(), "%sd MCP server %s\n", strings.ToUpper(use[:1])+use[1:], name)
+				}
+
+				return nil
+			})
+		},
+	}
+}
+
+func newMCPRmCmd(root *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:     "rm <name>...",
+		Aliases: []string{"delete"},
+		Short:   "Remove MCP servers",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMCPConfigSvr(root, func(_ *App, svr *client.MCPConfigSvr) error {
+				for _, name := range args {
+					if svr.MCPServerConfigByName(name) == nil {
+						return fmt.Errorf("MCP server %s not found", name)
+					}
+
+					if err := svr.DeleteMCPServerConfigByName(name); err != nil {
+						return fmt.Errorf("failed to remove MCP server %s: %w", name, err)
+					}
+
+					fmt.Fprintf(cmd.OutOrStdout(), "Removed MCP server %s\n", name)
+				}
+
+				return nil
+			})
+		},
+	}
+}
+
+func newMCPTestCmd(root *rootOptions) *cobra.Command {
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "test <name>",
+		Short: "Connect to an MCP server, list its tools, resources and templates, and report latency",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMCPConfigSvr(root, func(app *App, svr *client.MCPConfigSvr) error {
+				item := svr.MCPServerConfigByName(args[0])
+				if item == nil {
+					return fmt.Errorf("MCP server %s not found", args[0])
+				}
+
+				ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+				defer cancel()
+
+				probe, err := client.NewMCPSvrManager(app.MCPRepo, app.Logger).Probe(ctx, item)
+				if err != nil {
+					return err
+				}
+
+				printMCPProbe(cmd.OutOrStdout(), item, probe)
+
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().DurationVarP(&timeout, "timeout", "t", DefaultMCPTestTimeout, "timeout of the whole test")
+
+	return cmd
+}
+
+// printMCPProbe prints the result of `mcp test`
+func printMCPProbe(out io.Writer, item *client.MCPSvrItem, probe *client.MCPSvrProbe) {
+	fmt.Fprintf(out, "Server:    %s (%s %s)\n", item.Name, item.Type, mcpTarget(item))
+	if probe.Server != nil {
+		fmt.Fprintf(out, "Reports:   %s %s\n", probe.Server.Name, probe.Server.Version)
+	}
+	if !item.IsActive {
+		fmt.Fprintln(out, "Note:      the server is disabled and not connected in chats")
+	}
+	fmt.Fprintf(out, "Connect:   %s\n", probe.Connect.Round(time.Millisecond))
+
+	names := make([]string, 0, len(probe.Tools))
+	for _, tool := range probe.Tools {
+		names = append(names, tool.Name)
+	}
+	printMCPProbeStep(out, "Tools", names, probe.ToolsLatency, probe.ToolsErr)
+
+	names = make([]string, 0, len(probe.Resources))
+	for _, resource := range probe.Resources {
+		names = append(names, resource.URI)
+	}
+	printMCPProbeStep(out, "Resources", names, probe.ResourcesLatency, probe.ResourcesErr)
+
+	names = make([]string, 0, len(probe.ResourceTemplates))
+	for _, template := range probe.ResourceTemplates {
+		names = append(names, template.URITemplate)
+	}
+	printMCPProbeStep(out, "Templates", names, probe.ResourceTemplatesLatency, probe.ResourceTemplatesErr)
+}
+
+func printMCPProbeStep(out io.Writer, title string, names []string, latency time.Duration, err error) {
+	if err != nil {
+		fmt.Fprintf(out, "%-10s error after %s: %v\n", title+":", latency.Round(time.Millisecond), err)
+		return
+	}
+
+	fmt.Fprintf(out, "%-10s %d in %s\n", title+":", len(names), latency.Round(time.Millisecond))
+	for _, name := range names {
+		fmt.Fprintf(out, "  - %s\n", name)
+	}
+}
+
+// mcpTarget returns the command line or the URL the server is reached at
+func mcpTarget(item *client.MCPSvrItem) string {
+	if item.Type == client.ServerTypeStdio || item.BaseURL == "" {
+		return strings.TrimSpace(item.Command + " " + strings.Join(item.Args, " "))
+	}
+
+	return item.BaseURL
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// executeRootCmd runs the root command with args and returns its stdout
+func executeRootCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	out := &bytes.Buffer{}
+
+	cmd := NewRootCmd()
+	cmd.SetArgs(args)
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetOut(out)
+	cmd.SetErr(&bytes.Buffer{})
+
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestMCPCmd(t *testing.T) {
+	dir := newTestConfigDir(t, "http://localhost")
+
+	steps := []struct {
+		args     []string
+		contains []string
+		wantErr  bool
+	}{
+		{
+			args:     []string{"mcp", "add", "stdio", "fs", "-d", "files", "--", "npx", "-y", "server-fs", "/tmp"},
+			contains: []string{"Added MCP server fs (npx -y server-fs /tmp)"},
+		},
+		{
+			args:     []string{"mcp", "add", "http", "search", "https://example.com/mcp", "--disabled"},
+			contains: []string{"Added MCP server search (https://example.com/mcp)"},
+		},
+		{args: []string{"mcp", "add", "stdio", "fs", "--", "uvx", "other"}, wantErr: true},
+		{args: []string{"mcp", "add", "stdio", "no-dash", "uvx"}, wantErr: true},
+		{args: []string{"mcp", "add", "http", "bad", "localhost:8080"}, wantErr: true},
+		{
+			args: []string{"mcp", "list"},
+			contains: []string{
+				"todo    stdio           true    uvx mcp-todo",
+				"fs      stdio           true    npx -y server-fs /tmp    files",
+				"search  streamableHttp  false   https://example.com/mcp",
+			},
+		},
+		{
+			args:     []string{"mcp", "disable", "fs"},
+			contains: []string{"Disabled MCP server fs"},
+		},
+		{
+			args:     []string{"mcp", "enable", "search"},
+			contains: []string{"Enabled MCP server search"},
+		},
+		{
+			args:     []string{"mcp", "list"},
+			contains: []string{"fs      stdio           false", "search  streamableHttp  true"},
+		},
+		{args: []string{"mcp", "enable", "missing"}, wantErr: true},
+		{
+			args:     []string{"mcp", "rm", "fs"},
+			contains: []string{"Removed MCP server fs"},
+		},
+		{args: []string{"mcp", "rm", "fs"}, wantErr: true},
+		{args: []string{"mcp", "test", "fs"}, wantErr: true},
+	}
+
+	for _, step := range steps {
+		name := strings.Join(step.args, " ")
+		out, err := executeRootCmd(t, append([]string{"--config-dir", dir}, step.args...)...)
+		if (err != nil) != step.wantErr {
+			t.Fatalf("%s: error = %v, wantErr %v", name, err, step.wantErr)
+		}
+		for _, want := range step.contains {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: output should contain %q, got:\n%s", name, want, out)
+			}
+		}
+	}
+}
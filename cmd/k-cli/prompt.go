@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kydenul/K-CLI/client"
+)
+
+// DefaultEditor is the editor used by `prompt edit` when neither $VISUAL nor $EDITOR is set
+const DefaultEditor = "vi"
+
+// NewPromptCmd returns the prompt subcommand family, which manages the prompts
+func NewPromptCmd(root *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prompt",
+		Short: "Manage the prompts",
+	}
+
+	cmd.AddCommand(
+		newPromptListCmd(root),
+		newPromptShowCmd(root),
+		newPromptEditCmd(root),
+		newPromptImportCmd(root),
+		newPromptExportCmd(root),
+	)
+
+	return cmd
+}
+
+// withPromptSvr runs fn with a prompt service backed by the prompt repository of the app
+func withPromptSvr(root *rootOptions, fn func(svr *client.PromptSvr) error) error {
+	app, err := NewApp(root.configDir, root.profile)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	return fn(client.NewPromptSvr(app.PromptRepo, app.Logger))
+}
+
+// promptByName returns the prompt with the specified name, or an error if it does not exist
+func promptByName(svr *client.PromptSvr, name string) (*client.PromptItem, error) {
+	prompt := svr.PromptByName(name)
+	if prompt == nil {
+		return nil, fmt.Errorf("prompt %s not found", name)
+	}
+
+	return prompt, nil
+}
+
+func newPromptListCmd(root *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the prompts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withPromptSvr(root, func(svr *client.PromptSvr) error {
+				prompts := svr.AllPrompts()
+				sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "NAME\tSIZE\tDESCRIPTION")
+				for _, prompt := range prompts {
+					fmt.Fprintf(w, "%s\t%d\t%s\n", prompt.Name, len(prompt.Content), prompt.Description)
+				}
+
+				return w.Flush()
+			})
+		},
+	}
+}
+
+func newPromptShowCmd(root *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <name>",
+		Short: "Show the content of a prompt",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withPromptSvr(root, func(svr *client.PromptSvr) error {
+				prompt, err := promptByName(svr, args[0])
+				if err != nil {
+					return err
+				}
+
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), strings.TrimRight(prompt.Content, "\n"))
+				return err
+			})
+		},
+	}
+}
+
+func newPromptEditCmd(root *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "edit <name>",
+		Short: "Edit a prompt in $EDITOR",
+		Long: "Edit a prompt in $VISUAL or $EDITOR (default " + DefaultEditor + ").\n\n" +
+			"The prompt is opened as Markdown with its name and description in a front matter,\n" +
+			"changing the name renames the prompt.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withPromptSvr(root, func(svr *client.PromptSvr) error {
+				prompt, err := promptByName(svr, args[0])
+				if err != nil {
+					return err
+				}
+
+				original := client.PromptToMarkdown(prompt)
+				edited, err := editInEditor(cmd, prompt.Name+"-*.md", original)
+				if err != nil {
+					return err
+				}
+				if edited == original {
+					fmt.Fprintf(cmd.OutOrStdout(), "Prompt %s not changed\n", prompt.Name)
+					return nil
+				}
+
+				updated, err := client.ParsePromptMarkdown(edited)
+				if err != nil {
+					return fmt.Errorf("failed to parse the edited prompt: %w", err)
+				}
+				if updated.Name == "" {
+					updated.Name = prompt.Name
+				}
+				if updated.Name != prompt.Name {
+					if err := svr.RenamePrompt(prompt.Name, updated); err != nil {
+						return fmt.Errorf("failed to rename prompt %s: %w", prompt.Name, err)
+					}
+				} else if err := svr.AddPrompt(updated); err != nil {
+					return fmt.Errorf("failed to update prompt %s: %w", updated.Name, err)
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "Updated prompt %s\n", updated.Name)
+
+				return nil
+			})
+		},
+	}
+}
+
+// editInEditor opens content in the editor of the user and returns the edited content
+// editorCommand returns the command of $VISUAL, else $EDITOR, else DefaultEditor, split into its arguments.
+// The editor may carry arguments, e.g. `code --wait`, a blank variable is ignored.
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+
+	return []string{DefaultEditor}
+}
+
+func editInEditor(cmd *cobra.Command, pattern, content string) (string, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(content); err != nil {
+		_ = file.Close()
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	fields := editorCommand()
+	editor := strings.Join(fields, " ")
+	command := exec.CommandContext(cmd.Context(), fields[0], append(fields[1:], file.Name())...) //nolint:gosec
+	command.Stdin = cmd.InOrStdin()
+	command.Stdout = cmd.OutOrStdout()
+	command.Stderr = cmd.ErrOrStderr()
+	if err := command.Run(); err != nil {
+		return "", fmt.Errorf("failed to run editor %s: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read temp file: %w", err)
+	}
+
+	return string(data), nil
+}
+
+func newPromptImportCmd(root *rootOptions) *cobra.Command {
+	var (
+		name, description string
+		force             bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import <file.md>",
+		Short: "Import a prompt from a Markdown file",
+		Long: "Import a prompt from a Markdown file.\n\n" +
+			"The name and description are taken from the flags, then from the front matter of the file,\n" +
+			"the name defaults to the file name without extension.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0]) //nolint:gosec
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", args[0], err)
+			}
+
+			prompt, err := client.ParsePromptMarkdown(string(data))
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", args[0], err)
+			}
+			if name != "" {
+				prompt.Name = name
+			}
+			if prompt.Name == "" {
+				prompt.Name = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+			}
+			if cmd.Flags().Changed("description") {
+				prompt.Description = description
+			}
+
+			return withPromptSvr(root, func(svr *client.PromptSvr) error {
+				if !force && svr.PromptByName(prompt.Name) != nil {
+					return fmt.Errorf("prompt %s already exists, use --force to overwrite it", prompt.Name)
+				}
+
+				if err := svr.AddPrompt(prompt); err != nil {
+					return fmt.Errorf("failed to import prompt %s: %w", prompt.Name, err)
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "Imported prompt %s\n", prompt.Name)
+
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&name, "name", "n", "", "name of the prompt")
+	cmd.Flags().StringVarP(&description, "description", "d", "", "description of the prompt")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "overwrite the prompt if it exists")
+
+	return cmd
+}
+
+func newPromptExportCmd(root *rootOptions) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "export <name>",
+		Short: "Export a prompt as Markdown",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withPromptSvr(root, func(svr *client.PromptSvr) error {
+				prompt, err := promptByName(svr, args[0])
+				if err != nil {
+					return err
+				}
+
+				content := client.PromptToMarkdown(prompt)
+				if output == "" {
+					_, err := fmt.Fprint(cmd.OutOrStdout(), content)
+					return err
+				}
+
+				if err := os.WriteFile(output, []byte(content), 0o600); err != nil {
+					return fmt.Errorf("failed to write %s: %w", output, err)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "Exported prompt %s to %s\n", prompt.Name, output)
+
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write to the file instead of stdout")
+
+	return cmd
+}
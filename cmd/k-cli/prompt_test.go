@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPromptCmd(t *testing.T) {
+	dir := newTestConfigDir(t, "http://localhost")
+
+	file := filepath.Join(t.TempDir(), "review.md")
+	if err := os.WriteFile(file, []byte("Review the diff.\n"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	exported := filepath.Join(t.TempDir(), "exported.md")
+
+	// NOTE: The editor replaces the description and the content
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\nprintf -- '---\\nname: review\\ndescription: strict\\n---\\nBe strict.\\n' > \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0o700); err != nil { //nolint:gosec
+		t.Fatalf("failed to write editor: %v", err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+
+	steps := []struct {
+		args     []string
+		contains []string
+		wantErr  bool
+	}{
+		{
+			args:     []string{"prompt", "import", file, "-d", "code review"},
+			contains: []string{"Imported prompt review"},
+		},
+		{args: []string{"prompt", "import", file}, wantErr: true},
+		{
+			args:     []string{"prompt", "list"},
+			contains: []string{"deep-research", "mcp", "review         16    code review"},
+		},
+		{
+			args:     []string{"prompt", "show", "review"},
+			contains: []string{"Review the diff.\n"},
+		},
+		{
+			args:     []string{"prompt", "edit", "review"},
+			contains: []string{"Updated prompt review"},
+		},
+		{
+			args:     []string{"prompt", "export", "review"},
+			contains: []string{"---\nname: review\ndescription: strict\n---\nBe strict.\n"},
+		},
+		{args: []string{"prompt", "export", "review", "-o", exported}},
+		{
+			args:     []string{"prompt", "import", exported, "--name", "copy"},
+			contains: []string{"Imported prompt copy"},
+		},
+		{
+			args:     []string{"prompt", "show", "copy"},
+			contains: []string{"Be strict."},
+		},
+		{args: []string{"prompt", "show", "missing"}, wantErr: true},
+		{args: []string{"prompt", "edit", "missing"}, wantErr: true},
+	}
+
+	for _, step := range steps {
+		name := strings.Join(step.args, " ")
+		out, err := executeRootCmd(t, append([]string{"--config-dir", dir}, step.args...)...)
+		if (err != nil) != step.wantErr {
+			t.Fatalf("%s: error = %v, wantErr %v", name, err, step.wantErr)
+		}
+		for _, want := range step.contains {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: output should contain %q, got:\n%s", name, want, out)
+			}
+		}
+	}
+}
+
+func TestPromptCmd_EditRename(t *testing.T) {
+	dir := newTestConfigDir(t, "http://localhost")
+
+	file := filepath.Join(t.TempDir(), "review.md")
+	if err := os.WriteFile(file, []byte("Review the diff.\n"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := executeRootCmd(t, "--config-dir", dir, "prompt", "import", file); err != nil {
+		t.Fatalf("prompt import error = %v", err)
+	}
+
+	// NOTE: The editor renames the prompt, the arguments of $EDITOR are kept
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\nprintf -- '---\\nname: %s\\n---\\nBe strict.\\n' \"$1\" > \"$2\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0o700); err != nil { //nolint:gosec
+		t.Fatalf("failed to write editor: %v", err)
+	}
+	t.Setenv("VISUAL", "  ")
+	t.Setenv("EDITOR", editor+" mcp")
+
+	if _, err := executeRootCmd(t, "--config-dir", dir, "prompt", "edit", "review"); err == nil {
+		t.Error("prompt edit renaming to an existing prompt error = nil")
+	}
+
+	t.Setenv("EDITOR", editor+" strict-review")
+	out, err := executeRootCmd(t, "--config-dir", dir, "prompt", "edit", "review")
+	if err != nil || !strings.Contains(out, "Updated prompt strict-review") {
+		t.Fatalf("prompt edit = %q, %v, want the prompt renamed", out, err)
+	}
+
+	if _, err := executeRootCmd(t, "--config-dir", dir, "prompt", "show", "review"); err == nil {
+		t.Error("prompt show of the old name error = nil")
+	}
+	if out, err := executeRootCmd(t, "--config-dir", dir, "prompt", "show", "strict-review"); err != nil ||
+		!strings.Contains(out, "Be strict.") {
+		t.Errorf("prompt show of the new name = %q, %v", out, err)
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	t.Setenv("VISUAL", " ")
+	t.Setenv("EDITOR", "\t")
+	if fields := editorCommand(); len(fields) != 1 || fields[0] != DefaultEditor {
+		t.Errorf("editorCommand() with blank variables = %v, want %s", fields, DefaultEditor)
+	}
+
+	t.Setenv("EDITOR", "code --wait")
+	if fields := editorCommand(); strings.Join(fields, " ") != "code --wait" {
+		t.Errorf("editorCommand() = %v, want code --wait", fields)
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/chzyer/readline"
+
+	"github.com/kydenul/K-CLI/client"
+)
+
+const (
+	PromptPrimary  = ">>> "
+	PromptContinue = "... "
+
+	// MultiLineDelimiter opens and closes a multi-line input block
+	MultiLineDelimiter = `"""`
+	// LineContinuation at the end of a line continues the input on the next line
+	LineContinuation = `\`
+
+	// MaxToolResultSummary is the maximum number of runes of a tool result printed in the session
+	MaxToolResultSummary = 80
+
+	// ReasoningStyle dims the reasoning printed in the session, ReasoningStyleReset restores the style
+	ReasoningStyle      = "\x1b[2m"
+	ReasoningStyleReset = "\x1b[0m"
+)
+
+// lineReader reads one line of user input, implemented by *readline.Instance
+type lineReader interface {
+	SetPrompt(prompt string)
+	Readline() (string, error)
+}
+
+// REPL is the interactive read-eval-print loop of k-cli
+type REPL struct {
+	app      *App
+	mgr      *client.Manager
+	commands *client.CommandRegistry
+
+	out           io.Writer
+	hideReasoning bool // 不显示模型的推理内容
+}
+
+func NewREPL(app *App, chatID string, hideReasoning bool, out io.Writer) (*REPL, error) {
+	mgr, err := app.NewManager(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &REPL{
+		app:      app,
+		mgr:      mgr,
+		commands: client.NewCommandRegistry(),
+
+		out:           out,
+		hideReasoning: hideReasoning,
+	}, nil
+}
+
+// Run reads user input until EOF (Ctrl-D), sending each input to Manager.HandleUserTextInput
+func (r *REPL) Run() error {
+	defer r.mgr.MCPMgr.ClossAllSession()
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:            PromptPrimary,
+		HistoryFile:       r.app.HistoryPath(),
+		HistorySearchFold: true,
+		InterruptPrompt:   "^C",
+		EOFPrompt:         "exit",
+		AutoComplete:      r.completer(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize readline: %w", err)
+	}
+	defer rl.Close()
+
+	// NOTE: The files of the config dir changed during the session are reloaded before the next turn
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := r.mgr.Watch(ctx); err != nil {
+		r.app.Logger.Warnf("failed to watch the config files: %v", err)
+	}
+
+	fmt.Fprintf(r.out, "K-CLI %s. Type %shelp for commands, Ctrl-D to quit. "+
+		"Wrap multi-line input in %s or end lines with %s\n\n",
+		Version, client.CommandPrefix, MultiLineDelimiter, LineContinuation)
+
+	for {
+		input, err := readInput(rl)
+		switch {
+		case errors.Is(err, readline.ErrInterrupt):
+			// NOTE: Ctrl-C discards the current input
+			continue
+
+		case errors.Is(err, io.EOF):
+			return nil
+
+		case err != nil:
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+
+		if input == "" {
+			continue
+		}
+
+		if r.commands.IsCommand(input) {
+			if r.handleCommand(input) {
+				return nil
+			}
+			continue
+		}
+
+		if r.handleInput(input) {
+			return nil
+		}
+	}
+}
+
+// handleCommand executes a slash command, returning true if the command asked to quit
+func (r *REPL) handleCommand(input string) bool {
+	err := r.commands.Execute(context.Background(), r.mgr, input, r.out)
+	switch {
+	case errors.Is(err, client.ErrExit):
+		return true
+
+	case err != nil:
+		fmt.Fprintf(r.out, "Error: %v\n", err)
+	}
+
+	fmt.Fprintln(r.out)
+
+	return false
+}
+
+// completer completes the names of the registered slash commands
+func (r *REPL) completer() readline.AutoCompleter {
+	items := make([]readline.PrefixCompleterInterface, 0, 16)
+	for _, cmd := range r.commands.Commands() {
+		items = append(items, readline.PcItem(client.CommandPrefix+cmd.Name))
+	}
+
+	return readline.NewPrefixCompleter(items...)
+}
+
+// handleInput sends input to the manager, returning true if the user asked to quit with Ctrl-C.
+// The first Ctrl-C aborts the current turn, the second one quits if the turn is still running.
+func (r *REPL) handleInput(input string) bool {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+
+		printEvents(r.out, r.mgr.StreamUserTextInput(ctx, input), r.hideReasoning)
+	}()
+
+	interrupted := false
+	for {
+		select {
+		case <-doneCh:
+			return false
+
+		case <-sigCh:
+			if interrupted {
+				fmt.Fprintln(r.out, "\nQuit.")
+				return true
+			}
+
+			interrupted = true
+			cancel()
+			fmt.Fprintln(r.out, "\nAborting the current turn, press Ctrl-C again to quit.")
+		}
+	}
+}
+
+// printEvents renders the events of a turn as they arrive: the reasoning dimmed unless hideReasoning,
+// the answer token by token, and one line per MCP tool call with the first line of its result
+func printEvents(out io.Writer, events <-chan *client.Event, hideReasoning bool) {
+	// NOTE: The reasoning is rendered dimmed before the answer, each block ends with a blank line
+	const (
+		blockNone = iota
+		blockReasoning
+		blockAnswer
+	)
+	block := blockNone
+	endBlock := func() {
+		switch block {
+		case blockReasoning:
+			fmt.Fprint(out, ReasoningStyleReset+"\n\n")
+		case blockAnswer:
+			fmt.Fprint(out, "\n\n")
+		}
+		block = blockNone
+	}
+
+	for event := range events {
+		switch event.Type {
+		case client.EventReasoningDelta:
+			if hideReasoning {
+				continue
+			}
+			if block != blockReasoning {
+				endBlock()
+				fmt.Fprint(out, "💭 "+ReasoningStyle)
+				block = blockReasoning
+			}
+			fmt.Fprint(out, event.Delta)
+
+		case client.EventTextDelta:
+			if block != blockAnswer {
+				endBlock()
+				fmt.Fprint(out, "🤖 Assistant: ")
+				block = blockAnswer
+			}
+			fmt.Fprint(out, event.Delta)
+
+		case client.EventToolCallStarted:
+			endBlock()
+			args, _ := sonic.MarshalString(event.ToolCall.Arguments)
+			fmt.Fprintf(out, "🔧 Calling %s/%s %s\n", event.ToolCall.Server, event.ToolCall.Tool, args)
+
+		case client.EventToolResult:
+			fmt.Fprintf(out, "   ↳ %s\n\n", summarizeToolResult(event.ToolCall.Result))
+
+		case client.EventReloaded:
+			endBlock()
+			file := filepath.Base(event.Reload.Path)
+			switch {
+			case event.Reload.Err != nil:
+				fmt.Fprintf(out, "⚠️  Failed to reload %s: %v\n\n", file, event.Reload.Err)
+			case len(event.Reload.Servers) > 0:
+				fmt.Fprintf(out, "🔄 Reloaded %s, reconnected %s\n\n", file, strings.Join(event.Reload.Servers, ", "))
+			default:
+				fmt.Fprintf(out, "🔄 Reloaded %s\n\n", file)
+			}
+
+		case client.EventTurnFinished:
+			endBlock()
+
+		case client.EventError:
+			endBlock()
+			if errors.Is(event.Err, context.Canceled) {
+				fmt.Fprint(out, "Aborted.\n\n")
+			} else {
+				fmt.Fprintf(out, "Error: %v\n\n", event.Err)
+			}
+		}
+	}
+}
+
+// summarizeToolResult returns the first line of the tool result, truncated to MaxToolResultSummary runes
+func summarizeToolResult(result string) string {
+	line, _, more := strings.Cut(strings.TrimSpace(result), "\n")
+	if runes := []rune(line); len(runes) > MaxToolResultSummary {
+		return string(runes[:MaxToolResultSummary]) + "…"
+	}
+	if more {
+		return line + " …"
+	}
+
+	return line
+}
+
+// readInput reads a complete user input, which is either a single line, a block wrapped
+// in MultiLineDelimiter, or lines joined by a trailing LineContinuation
+func readInput(lr lineReader) (string, error) {
+	lr.SetPrompt(PromptPrimary)
+	defer lr.SetPrompt(PromptPrimary)
+
+	line, err := lr.Readline()
+	if err != nil {
+		return "", err
+	}
+
+	// NOTE: Multi-line block
+	if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, MultiLineDelimiter) {
+		first := strings.TrimPrefix(trimmed, MultiLineDelimiter)
+		if strings.HasSuffix(first, MultiLineDelimiter) && first != "" {
+			return strings.TrimSpace(strings.TrimSuffix(first, MultiLineDelimiter)), nil
+		}
+
+		lines := make([]string, 0, 8)
+		if first != "" {
+			lines = append(lines, first)
+		}
+
+		lr.SetPrompt(PromptContinue)
+		for {
+			line, err := lr.Readline()
+			if err != nil {
+				return "", err
+			}
+
+			if before, found := strings.CutSuffix(strings.TrimRight(line, " \t"), MultiLineDelimiter); found {
+				if before != "" {
+					lines = append(lines, before)
+				}
+
+				return strings.TrimSpace(strings.Join(lines, "\n")), nil
+			}
+
+			lines = append(lines, line)
+		}
+	}
+
+	// NOTE: Line continuation
+	lines := make([]string, 0, 8)
+	for {
+		before, found := strings.CutSuffix(line, LineContinuation)
+		if !found {
+			lines = append(lines, line)
+			break
+		}
+		lines = append(lines, before)
+
+		lr.SetPrompt(PromptContinue)
+		if line, err = lr.Readline(); err != nil {
+			return "", err
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
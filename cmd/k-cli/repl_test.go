@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kydenul/K-CLI/client"
+)
+
+// fakeLineReader replays the given lines and returns io.EOF afterwards
+type fakeLineReader struct {
+	lines   []string
+	prompts []string
+}
+
+func (f *fakeLineReader) SetPrompt(prompt string) { f.prompts = append(f.prompts, prompt) }
+
+func (f *fakeLineReader) Readline() (string, error) {
+	if len(f.lines) == 0 {
+		return "", io.EOF
+	}
+
+	line := f.lines[0]
+	f.lines = f.lines[1:]
+
+	return line, nil
+}
+
+func TestReadInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "single line",
+			lines:    []string{"  hello world  "},
+			expected: "hello world",
+		},
+		{
+			name:     "line continuation",
+			lines:    []string{`first \`, `second \`, "third"},
+			expected: "first \nsecond \nthird",
+		},
+		{
+			name:     "multi-line block",
+			lines:    []string{`"""`, "line 1", "", "line 2", `"""`},
+			expected: "line 1\n\nline 2",
+		},
+		{
+			name:     "multi-line block with inline delimiters",
+			lines:    []string{`"""line 1`, `line 2"""`},
+			expected: "line 1\nline 2",
+		},
+		{
+			name:     "single line block",
+			lines:    []string{`"""hello"""`},
+			expected: "hello",
+		},
+		{
+			name:    "unterminated block",
+			lines:   []string{`"""`, "line 1"},
+			wantErr: true,
+		},
+		{
+			name:    "eof",
+			lines:   nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lr := &fakeLineReader{lines: tt.lines}
+
+			got, err := readInput(lr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("readInput() = %q, want %q", got, tt.expected)
+			}
+
+			if last := lr.prompts[len(lr.prompts)-1]; last != PromptPrimary {
+				t.Errorf("readInput() should restore prompt to %q, got %q", PromptPrimary, last)
+			}
+		})
+	}
+}
+
+func TestPrintEvents(t *testing.T) {
+	tests := []struct {
+		name          string
+		events        []*client.Event
+		hideReasoning bool
+		expected      string
+	}{
+		{
+			name:          "answer with tool call",
+			hideReasoning: true,
+			events: []*client.Event{
+				{Type: client.EventReasoningDelta, Delta: "hidden"},
+				{Type: client.EventTextDelta, Delta: "Let me "},
+				{Type: client.EventTextDelta, Delta: "check."},
+				{Type: client.EventToolCallStarted, ToolCall: &client.ToolCallEvent{
+					Server: "test", Tool: "echo", Arguments: map[string]any{"text": "hi"},
+				}},
+				{Type: client.EventToolResult, ToolCall: &client.ToolCallEvent{
+					Server: "test", Tool: "echo", Result: "echo: hi\nsecond line",
+				}},
+				{Type: client.EventTextDelta, Delta: "Done."},
+				{Type: client.EventTurnFinished, Message: client.NewMessageWithOption(client.RoleAssistant, "Done.", nil)},
+			},
+			expected: "🤖 Assistant: Let me check.\n\n" +
+				"🔧 Calling test/echo {\"text\":\"hi\"}\n" +
+				"   ↳ echo: hi …\n\n" +
+				"🤖 Assistant: Done.\n\n",
+		},
+		{
+			name: "reasoning",
+			events: []*client.Event{
+				{Type: client.EventReasoningDelta, Delta: "Think"},
+				{Type: client.EventReasoningDelta, Delta: "ing."},
+				{Type: client.EventTextDelta, Delta: "Answer."},
+				{Type: client.EventTurnFinished, Message: client.NewMessageWithOption(client.RoleAssistant, "Answer.", nil)},
+			},
+			expected: "💭 " + ReasoningStyle + "Thinking." + ReasoningStyleReset + "\n\n" +
+				"🤖 Assistant: Answer.\n\n",
+		},
+		{
+			name: "aborted",
+			events: []*client.Event{
+				{Type: client.EventTextDelta, Delta: "Partial"},
+				{Type: client.EventError, Err: fmt.Errorf("OpenAI: %w", context.Canceled)},
+			},
+			expected: "🤖 Assistant: Partial\n\nAborted.\n\n",
+		},
+		{
+			name: "reloaded",
+			events: []*client.Event{
+				{Type: client.EventReloaded, Reload: &client.ReloadEvent{
+					Path: "/tmp/k-cli/mcp_servers.jsonl", Servers: []string{"fetch", "search"},
+				}},
+				{Type: client.EventReloaded, Reload: &client.ReloadEvent{Path: "/tmp/k-cli/prompts.jsonl"}},
+				{Type: client.EventReloaded, Reload: &client.ReloadEvent{
+					Path: "/tmp/k-cli/client.yaml", Err: errors.New("invalid"),
+				}},
+				{Type: client.EventTextDelta, Delta: "Answer."},
+				{Type: client.EventTurnFinished, Message: client.NewMessageWithOption(client.RoleAssistant, "Answer.", nil)},
+			},
+			expected: "🔄 Reloaded mcp_servers.jsonl, reconnected fetch, search\n\n" +
+				"🔄 Reloaded prompts.jsonl\n\n" +
+				"⚠️  Failed to reload client.yaml: invalid\n\n" +
+				"🤖 Assistant: Answer.\n\n",
+		},
+		{
+			name:     "error",
+			events:   []*client.Event{{Type: client.EventError, Err: errors.New("boom")}},
+			expected: "Error: boom\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := make(chan *client.Event, len(tt.events))
+			for _, event := range tt.events {
+				events <- event
+			}
+			close(events)
+
+			out := &strings.Builder{}
+			printEvents(out, events, tt.hideReasoning)
+			if out.String() != tt.expected {
+				t.Errorf("printEvents() = %q, want %q", out.String(), tt.expected)
+			}
+		})
+	}
+}
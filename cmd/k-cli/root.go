@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// rootOptions holds the persistent flags shared by all subcommands
+type rootOptions struct {
+	configDir     string
+	chatID        string
+	hideReasoning bool
+	profile       string
+}
+
+// NewRootCmd returns the k-cli root command, which starts the interactive REPL
+func NewRootCmd() *cobra.Command {
+	opts := &rootOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "k-cli",
+		Short: "K-CLI is an MCP client for chatting with LLMs from the terminal",
+		Long: "K-CLI is an MCP client for chatting with LLMs from the terminal.\n\n" +
+			"Running k-cli without a subcommand starts an interactive session.",
+		Version:      Version,
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			app, err := NewApp(opts.configDir, opts.profile)
+			if err != nil {
+				return err
+			}
+			defer app.Close()
+
+			repl, err := NewREPL(app, opts.chatID, opts.hideReasoning, cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+
+			return repl.Run()
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&opts.configDir, "config-dir", DefaultConfigDir,
+		"directory containing client.yaml, chats.jsonl, mcp_servers.jsonl and prompts.jsonl")
+	cmd.PersistentFlags().StringVar(&opts.profile, "profile", "",
+		"profile of client.yaml to use, default_profile if not set")
+	cmd.PersistentFlags().BoolVar(&opts.hideReasoning, "hide-reasoning", false,
+		"do not show the reasoning (thinking) of the model")
+	cmd.Flags().StringVarP(&opts.chatID, "chat", "c", "", "continue an existing chat by ID")
+
+	cmd.AddCommand(
+		NewAskCmd(opts),
+		NewChatsCmd(opts),
+		NewMCPCmd(opts),
+		NewPromptCmd(opts),
+	)
+
+	return cmd
+}
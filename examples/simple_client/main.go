@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 
@@ -62,7 +63,10 @@ func main() {
 	}
 	Logger.Info("PromptRepo initialized")
 
-	mgr := client.NewManager(Logger, chatRepo, mcpRepo, promptRepo, nil, config)
+	mgr, err := client.NewManager(Logger, chatRepo, mcpRepo, promptRepo, nil, config)
+	if err != nil {
+		Logger.Panicf("Manager initialized fail: %v", err)
+	}
 	// NOTE Clean up
 	defer func() {
 		if mgr.MCPMgr != nil {
@@ -70,14 +74,14 @@ func main() {
 		}
 	}()
 
-	resp, err := mgr.HandleUserTextInput("今天上海天气怎么样？")
+	resp, err := mgr.HandleUserTextInput(context.Background(), "今天上海天气怎么样？")
 	if err != nil {
 		Logger.Errorf("failed to run: %v", err)
 		return
 	}
 	Logger.Infof("First => Response: %s", resp.Content)
 
-	resp, err = mgr.HandleUserTextInput("今天上海天气怎么样？")
+	resp, err = mgr.HandleUserTextInput(context.Background(), "今天上海天气怎么样？")
 	if err != nil {
 		Logger.Errorf("failed to run: %v", err)
 		return